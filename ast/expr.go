@@ -118,6 +118,89 @@ func (b SuperExpr) Accept(visitor ExprVisitor) interface{} {
 	return visitor.VisitSuperExpr(b)
 }
 
+type ListExpr struct {
+	Bracket  Token
+	Elements []Expr
+}
+
+func (b ListExpr) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitListExpr(b)
+}
+
+type IndexExpr struct {
+	Object  Expr
+	Bracket Token
+	Index   Expr
+}
+
+func (b IndexExpr) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitIndexExpr(b)
+}
+
+type IndexSetExpr struct {
+	Object  Expr
+	Bracket Token
+	Index   Expr
+	Value   Expr
+}
+
+func (b IndexSetExpr) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitIndexSetExpr(b)
+}
+
+type MapExpr struct {
+	Brace  Token
+	Keys   []Expr
+	Values []Expr
+}
+
+func (b MapExpr) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitMapExpr(b)
+}
+
+type ConditionalExpr struct {
+	Condition  Expr
+	ThenBranch Expr
+	ElseBranch Expr
+}
+
+func (b ConditionalExpr) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitConditionalExpr(b)
+}
+
+type InterpolationExpr struct {
+	Parts []Expr
+}
+
+func (b InterpolationExpr) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitInterpolationExpr(b)
+}
+
+type FunctionExpr struct {
+	Keyword     Token
+	Params      []Token
+	Body        []Stmt
+	IsGenerator bool
+}
+
+func (b FunctionExpr) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitFunctionExpr(b)
+}
+
+// CompoundExpr is a compound assignment or increment of a variable, property
+// or index. Operator is the binary operator applied to the old value and Value;
+// a postfix increment evaluates to the old value.
+type CompoundExpr struct {
+	Target   Expr
+	Operator Token
+	Value    Expr
+	Postfix  bool
+}
+
+func (b CompoundExpr) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitCompoundExpr(b)
+}
+
 type ExprVisitor interface {
 	VisitBinaryExpr(expr BinaryExpr) interface{}
 	VisitGroupingExpr(expr GroupingExpr) interface{}
@@ -131,4 +214,12 @@ type ExprVisitor interface {
 	VisitSetExpr(expr SetExpr) interface{}
 	VisitThisExpr(expr ThisExpr) interface{}
 	VisitSuperExpr(expr SuperExpr) interface{}
+	VisitListExpr(expr ListExpr) interface{}
+	VisitIndexExpr(expr IndexExpr) interface{}
+	VisitIndexSetExpr(expr IndexSetExpr) interface{}
+	VisitMapExpr(expr MapExpr) interface{}
+	VisitConditionalExpr(expr ConditionalExpr) interface{}
+	VisitInterpolationExpr(expr InterpolationExpr) interface{}
+	VisitFunctionExpr(expr FunctionExpr) interface{}
+	VisitCompoundExpr(expr CompoundExpr) interface{}
 }
@@ -50,16 +50,34 @@ func (b IfStmt) Accept(visitor StmtVisitor) interface{} {
 type WhileStmt struct {
 	Condition Expr
 	Body      Stmt
+	Increment Expr
 }
 
 func (b WhileStmt) Accept(visitor StmtVisitor) interface{} {
 	return visitor.VisitWhileStmt(b)
 }
 
+// ForInStmt is 'for (var Name in Iterable) Body'. Start is the first token
+// of Iterable, which errors about the iterable are reported at.
+type ForInStmt struct {
+	Name     Token
+	Start    Token
+	Iterable Expr
+	Body     Stmt
+}
+
+func (b ForInStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitForInStmt(b)
+}
+
 type FunctionStmt struct {
-	Name   Token
-	Params []Token
-	Body   []Stmt
+	Name     Token
+	Params   []Token
+	Body     []Stmt
+	IsGetter bool
+	IsSetter bool
+	// a function whose body contains 'yield' returns a generator when called
+	IsGenerator bool
 }
 
 func (b FunctionStmt) Accept(visitor StmtVisitor) interface{} {
@@ -76,15 +94,82 @@ func (b ReturnStmt) Accept(visitor StmtVisitor) interface{} {
 }
 
 type ClassStmt struct {
-	Name       Token
-	Methods    []FunctionStmt
-	Superclass *VariableExpr
+	Name          Token
+	Methods       []FunctionStmt
+	StaticMethods []FunctionStmt
+	Fields        []VarStmt
+	Superclass    *VariableExpr
+	Traits        []VariableExpr
 }
 
 func (b ClassStmt) Accept(visitor StmtVisitor) interface{} {
 	return visitor.VisitClassStmt(b)
 }
 
+type BreakStmt struct {
+	Keyword Token
+}
+
+func (b BreakStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitBreakStmt(b)
+}
+
+type ContinueStmt struct {
+	Keyword Token
+}
+
+func (b ContinueStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitContinueStmt(b)
+}
+
+type ImportStmt struct {
+	Keyword Token
+	Path    Token
+	Name    Token
+}
+
+func (b ImportStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitImportStmt(b)
+}
+
+type ThrowStmt struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (b ThrowStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitThrowStmt(b)
+}
+
+type TryStmt struct {
+	Body        []Stmt
+	CatchName   *Token
+	CatchBody   []Stmt
+	FinallyBody []Stmt
+}
+
+func (b TryStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitTryStmt(b)
+}
+
+type TraitStmt struct {
+	Name    Token
+	Methods []FunctionStmt
+}
+
+func (b TraitStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitTraitStmt(b)
+}
+
+type YieldStmt struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (b YieldStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitYieldStmt(b)
+}
+
 type StmtVisitor interface {
 	VisitExpressionStmt(stmt ExpressionStmt) interface{}
 	VisitPrintStmt(stmt PrintStmt) interface{}
@@ -92,7 +177,15 @@ type StmtVisitor interface {
 	VisitBlockStmt(stmt BlockStmt) interface{}
 	VisitIfStmt(stmt IfStmt) interface{}
 	VisitWhileStmt(stmt WhileStmt) interface{}
+	VisitForInStmt(stmt ForInStmt) interface{}
 	VisitFunctionStmt(stmt FunctionStmt) interface{}
 	VisitReturnStmt(stmt ReturnStmt) interface{}
 	VisitClassStmt(stmt ClassStmt) interface{}
+	VisitBreakStmt(stmt BreakStmt) interface{}
+	VisitContinueStmt(stmt ContinueStmt) interface{}
+	VisitImportStmt(stmt ImportStmt) interface{}
+	VisitThrowStmt(stmt ThrowStmt) interface{}
+	VisitTryStmt(stmt TryStmt) interface{}
+	VisitTraitStmt(stmt TraitStmt) interface{}
+	VisitYieldStmt(stmt YieldStmt) interface{}
 }
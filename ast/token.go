@@ -19,6 +19,11 @@ const (
 	TokenSemicolon
 	TokenSlash
 	TokenStar
+	TokenPercent
+	TokenAmpersand
+	TokenBar
+	TokenCaret
+	TokenTilde
 
 	TokenColon
 	TokenQuestionMark
@@ -33,10 +38,22 @@ const (
 	TokenGreaterEqual
 	TokenLess
 	TokenLessEqual
+	TokenArrow
+	TokenPlusEqual
+	TokenMinusEqual
+	TokenStarEqual
+	TokenSlashEqual
+	TokenPlusPlus
+	TokenMinusMinus
+	TokenStarStar
+	TokenTildeSlash
+	TokenLessLess
+	TokenGreaterGreater
 
 	// literals
 	TokenIdentifier
 	TokenString
+	TokenInterpolation
 	TokenNumber
 
 	// keywords
@@ -61,6 +78,16 @@ const (
 	TokenBreak
 	TokenContinue
 	TokenTypeType
+	TokenImport
+	TokenAs
+	TokenThrow
+	TokenTry
+	TokenCatch
+	TokenFinally
+	TokenTrait
+	TokenWith
+	TokenIn
+	TokenYield
 )
 
 type Token struct {
@@ -69,6 +96,7 @@ type Token struct {
 	Literal   interface{}
 	Line      int
 	Start     int
+	File      string
 }
 
 func (t Token) String() string {
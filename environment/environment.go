@@ -27,6 +27,16 @@ func (e *Environment) Get(name string) (interface{}, error) {
 	return nil, ErrUndefined
 }
 
+// Root returns the outermost environment, which holds the globals of the
+// file this environment belongs to.
+func (e *Environment) Root() *Environment {
+	env := e
+	for env.Enclosing != nil {
+		env = env.Enclosing
+	}
+	return env
+}
+
 func (e *Environment) GetAt(distance int, name string) interface{} {
 	return e.ancestor(distance).values[name]
 }
@@ -9,6 +9,9 @@ import (
 type class struct {
 	name       string
 	methods    map[string]function
+	setters    map[string]function
+	statics    map[string]function
+	fields     map[string]interface{}
 	superclass *class
 }
 
@@ -43,6 +46,38 @@ func (c class) findMethod(name string) *function {
 	return nil
 }
 
+func (c class) Get(interpreter *Interpreter, name ast.Token) (interface{}, error) {
+	for cl := &c; cl != nil; cl = cl.superclass {
+		if val, ok := cl.fields[name.Lexeme]; ok {
+			return val, nil
+		}
+		if method, ok := cl.statics[name.Lexeme]; ok {
+			if method.declaration.IsGetter {
+				return method.call(interpreter, nil), nil
+			}
+			return method, nil
+		}
+	}
+
+	return nil, runtimeError{token: name, message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+}
+
+func (c class) set(name ast.Token, value interface{}) {
+	c.fields[name.Lexeme] = value
+}
+
+func (c class) findSetter(name string) *function {
+	if setter, ok := c.setters[name]; ok {
+		return &setter
+	}
+
+	if c.superclass != nil {
+		return c.superclass.findSetter(name)
+	}
+
+	return nil
+}
+
 func (c class) String() string {
 	return c.name
 }
@@ -59,6 +94,9 @@ func (i *instance) Get(interpreter *Interpreter, name ast.Token) (interface{}, e
 
 	method := i.class.findMethod(name.Lexeme)
 	if method != nil {
+		if method.declaration.IsGetter {
+			return method.bind(i).call(interpreter, nil), nil
+		}
 		return method.bind(i), nil
 	}
 
@@ -0,0 +1,117 @@
+package interpret
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/Pra1tik/golox/ast"
+)
+
+type dict struct {
+	keys   []interface{}
+	values map[interface{}]interface{}
+}
+
+func createDict() *dict {
+	return &dict{values: make(map[interface{}]interface{})}
+}
+
+func (d *dict) Get(interpreter *Interpreter, name ast.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "length":
+		return native{params: 0, fn: func(_ *Interpreter, _ []interface{}) interface{} {
+			return int64(len(d.keys))
+		}}, nil
+	case "keys":
+		return native{params: 0, fn: func(_ *Interpreter, _ []interface{}) interface{} {
+			keys := make([]interface{}, len(d.keys))
+			for i, key := range d.keys {
+				keys[i] = publicKey(key)
+			}
+			return &list{elements: keys}
+		}}, nil
+	case "values":
+		return native{params: 0, fn: func(_ *Interpreter, _ []interface{}) interface{} {
+			values := make([]interface{}, len(d.keys))
+			for i, key := range d.keys {
+				values[i] = d.values[key]
+			}
+			return &list{elements: values}
+		}}, nil
+	case "has":
+		return native{params: 1, fn: func(_ *Interpreter, args []interface{}) interface{} {
+			_, ok := d.values[d.checkKey(name, args[0])]
+			return ok
+		}}, nil
+	case "remove":
+		return native{params: 1, fn: func(_ *Interpreter, args []interface{}) interface{} {
+			key := d.checkKey(name, args[0])
+			value := d.values[key]
+			d.remove(key)
+			return value
+		}}, nil
+	}
+
+	return nil, runtimeError{token: name, message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+}
+
+func (d *dict) get(bracket ast.Token, key interface{}) interface{} {
+	key = d.checkKey(bracket, key)
+	value, ok := d.values[key]
+	if !ok {
+		panic(runtimeError{token: bracket, message: fmt.Sprintf("Undefined key '%s'.", fmt.Sprint(key))})
+	}
+	return value
+}
+
+func (d *dict) set(bracket ast.Token, key interface{}, value interface{}) {
+	key = d.checkKey(bracket, key)
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[key] = value
+}
+
+func (d *dict) remove(key interface{}) {
+	if _, ok := d.values[key]; !ok {
+		return
+	}
+	delete(d.values, key)
+	for i, k := range d.keys {
+		if k == key {
+			d.keys = append(d.keys[:i], d.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// bigKey is the canonical form of a big integer key, which is not comparable
+// by value as a *big.Int.
+type bigKey string
+
+// checkKey validates a key and returns it in canonical form: floats with an
+// integral value are stored as integers so that m[1] and m[1.0] agree.
+func (d *dict) checkKey(token ast.Token, key interface{}) interface{} {
+	switch k := key.(type) {
+	case float64:
+		if k == math.Trunc(k) && math.Abs(k) < 1<<63 {
+			return int64(k)
+		}
+		return key
+	case *big.Int:
+		return bigKey(k.String())
+	case string, int64, bool:
+		return key
+	}
+	panic(runtimeError{token: token, message: "Map keys must be strings, numbers or booleans."})
+}
+
+// publicKey converts a canonical map key back to the value it stands for.
+func publicKey(key interface{}) interface{} {
+	if k, ok := key.(bigKey); ok {
+		value, _ := new(big.Int).SetString(string(k), 10)
+		return value
+	}
+	return key
+}
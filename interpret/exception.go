@@ -0,0 +1,30 @@
+package interpret
+
+import (
+	"fmt"
+
+	"github.com/Pra1tik/golox/ast"
+)
+
+// exception is the value bound by a catch clause. It wraps both errors raised
+// by the interpreter and values thrown by 'throw'.
+type exception struct {
+	err runtimeError
+}
+
+func (e *exception) Get(interpreter *Interpreter, name ast.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "message":
+		return e.err.message, nil
+	case "line":
+		return int64(e.err.token.Line), nil
+	case "value":
+		return e.err.value, nil
+	}
+
+	return nil, runtimeError{token: name, message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+}
+
+func (e *exception) String() string {
+	return e.err.message
+}
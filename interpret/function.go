@@ -21,6 +21,10 @@ func (f function) arity() int {
 }
 
 func (f function) call(interp *Interpreter, args []interface{}) (returnVal interface{}) {
+	if f.declaration.IsGenerator {
+		return &generator{interp: interp, fn: f, args: args}
+	}
+
 	defer func() {
 		if err := recover(); err != nil {
 			if v, ok := err.(Return); ok {
@@ -61,5 +65,8 @@ func (f function) bind(i *instance) function {
 }
 
 func (f function) String() string {
+	if f.declaration.Name.Lexeme == "" {
+		return "<fn>"
+	}
 	return "<fn " + f.declaration.Name.Lexeme + ">"
 }
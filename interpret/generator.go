@@ -0,0 +1,191 @@
+package interpret
+
+import (
+	"fmt"
+
+	"github.com/Pra1tik/golox/ast"
+)
+
+// generator is the value returned by calling a function that contains
+// 'yield'. Its body runs on a goroutine of its own, which hands control back
+// and forth with the caller over channels so that only one of them runs at a
+// time. The body starts on the first call to hasNext() or next() and runs up
+// to each yield as values are asked for.
+//
+// A generator suspended at a yield holds on to its goroutine until it is
+// closed: a for-in loop closes the generator it iterates when it exits early,
+// and scripts can call close() themselves.
+type generator struct {
+	interp *Interpreter
+	fn     function
+	args   []interface{}
+
+	started bool
+	running bool
+	done    bool
+
+	// a value yielded by the body that next() has not returned yet
+	ready bool
+	value interface{}
+
+	resume  chan struct{}
+	cancel  chan struct{}
+	results chan generatorResult
+}
+
+// generatorClosed unwinds the body of a generator being closed. It is not a
+// runtimeError, so 'catch' can't intercept it, but 'finally' blocks still run.
+type generatorClosed struct{}
+
+// generatorResult is sent by the body each time it yields, finishes or fails.
+type generatorResult struct {
+	value interface{}
+	done  bool
+	err   interface{}
+}
+
+func (g *generator) Get(interpreter *Interpreter, name ast.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "hasNext":
+		return native{params: 0, fn: func(_ *Interpreter, _ []interface{}) interface{} {
+			g.checkNotRunning()
+			return g.hasNext()
+		}}, nil
+	case "next":
+		return native{params: 0, fn: func(_ *Interpreter, _ []interface{}) interface{} {
+			g.checkNotRunning()
+			if !g.hasNext() {
+				panic(nativeError("Generator is exhausted."))
+			}
+			return g.next()
+		}}, nil
+	case "close":
+		return native{params: 0, fn: func(_ *Interpreter, _ []interface{}) interface{} {
+			g.checkNotRunning()
+			g.close()
+			return nil
+		}}, nil
+	}
+
+	return nil, runtimeError{token: name, message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+}
+
+func (g *generator) hasNext() bool {
+	g.advance()
+	return g.ready
+}
+
+// next returns the buffered value; hasNext() must have reported true.
+func (g *generator) next() interface{} {
+	value := g.value
+	g.ready = false
+	g.value = nil
+	return value
+}
+
+// checkNotRunning rejects a generator being advanced from its own body,
+// which would otherwise deadlock.
+func (g *generator) checkNotRunning() {
+	if g.running {
+		panic(nativeError("Generator is already running."))
+	}
+}
+
+// advance runs the body until it yields a value or finishes, unless a value
+// is already waiting. Errors raised in the body are raised again here.
+func (g *generator) advance() {
+	if g.ready || g.done {
+		return
+	}
+
+	var result generatorResult
+	if !g.started {
+		g.started = true
+		g.resume = make(chan struct{})
+		g.cancel = make(chan struct{})
+		g.results = make(chan generatorResult)
+		result = g.transfer(func() { go g.run() })
+	} else {
+		result = g.transfer(func() { g.resume <- struct{}{} })
+	}
+
+	if result.err != nil {
+		g.done = true
+		panic(result.err)
+	}
+	if result.done {
+		g.done = true
+		return
+	}
+	g.ready, g.value = true, result.value
+}
+
+// close finishes the generator. A body suspended at a yield is unwound,
+// running its 'finally' blocks, so that its goroutine exits; errors raised
+// while unwinding are raised again here. A running generator is left alone,
+// since it can't be unwound from within its own body.
+func (g *generator) close() {
+	if g.done || g.running {
+		return
+	}
+	g.done = true
+	g.ready, g.value = false, nil
+	if !g.started {
+		return
+	}
+
+	if result := g.transfer(func() { close(g.cancel) }); result.err != nil {
+		panic(result.err)
+	}
+}
+
+// transfer hands control to the body through start and waits for its next
+// result, restoring the caller's interpreter state afterwards.
+func (g *generator) transfer(start func()) generatorResult {
+	environment, current := g.interp.environment, g.interp.generator
+	g.running = true
+	start()
+	result := <-g.results
+	g.running = false
+	g.interp.environment, g.interp.generator = environment, current
+	return result
+}
+
+func (g *generator) run() {
+	defer func() {
+		if err := recover(); err != nil {
+			if _, ok := err.(generatorClosed); ok {
+				g.results <- generatorResult{done: true}
+				return
+			}
+			g.results <- generatorResult{err: err}
+			return
+		}
+		g.results <- generatorResult{done: true}
+	}()
+
+	g.interp.generator = g
+	body := g.fn
+	body.declaration.IsGenerator = false
+	body.call(g.interp, g.args)
+}
+
+// yield hands value to the caller and blocks until the next value is asked
+// for or the generator is closed. It runs on the generator's goroutine.
+func (g *generator) yield(value interface{}) {
+	environment := g.interp.environment
+	g.results <- generatorResult{value: value}
+	select {
+	case <-g.resume:
+	case <-g.cancel:
+		panic(generatorClosed{})
+	}
+	g.interp.environment, g.interp.generator = environment, g
+}
+
+func (g *generator) String() string {
+	if g.fn.declaration.Name.Lexeme == "" {
+		return "<generator>"
+	}
+	return "<generator " + g.fn.declaration.Name.Lexeme + ">"
+}
@@ -3,6 +3,7 @@ package interpret
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/Pra1tik/golox/ast"
 	env "github.com/Pra1tik/golox/environment"
@@ -13,27 +14,67 @@ type Interpreter struct {
 	globals     *env.Environment
 	stdOut      io.Writer
 	stdErr      io.Writer
-	locals      map[ast.Expr]int
+	locals      map[ast.Token]int
+	modules     map[string]*module
+	imports     map[ast.Token]*module
+
+	// values whose string conversion is in progress, to stop self-reference
+	stringifying map[interface{}]bool
+	// the generator whose body is executing, if any
+	generator *generator
 }
 
 type runtimeError struct {
 	token   ast.Token
 	message string
+	value   interface{} // the value given to 'throw', if any
 }
 
 type Return struct {
 	Value interface{}
 }
 
+type Break struct{}
+
+type Continue struct{}
+
 func (r runtimeError) Error() string {
+	if r.token.File != "" {
+		return fmt.Sprintf("%s\n[line %d in %s]", r.message, r.token.Line, r.token.File)
+	}
 	return fmt.Sprintf("%s\n[line %d]", r.message, r.token.Line)
 }
 
 func CreateInterpreter(stdOut io.Writer, stdErr io.Writer) *Interpreter {
 	globals := env.CreateEnvironment(nil)
-	globals.Define("clock", clock{})
+	defineNatives(globals)
+
+	return &Interpreter{
+		globals:     globals,
+		environment: globals,
+		stdOut:      stdOut,
+		stdErr:      stdErr,
+		locals:      make(map[ast.Token]int),
+		modules:     make(map[string]*module),
+		imports:     make(map[ast.Token]*module),
+
+		stringifying: make(map[interface{}]bool),
+	}
+}
 
-	return &Interpreter{globals: globals, environment: globals, stdOut: stdOut, stdErr: stdErr, locals: make(map[ast.Expr]int)}
+func defineNatives(globals *env.Environment) {
+	globals.Define("clock", clock{})
+	globals.Define("str", native{params: 1, fn: func(interp *Interpreter, args []interface{}) interface{} {
+		return interp.stringify(args[0])
+	}})
+	globals.Define("range", native{params: 2, fn: func(_ *Interpreter, args []interface{}) interface{} {
+		start, isStartInt := asInt64(args[0])
+		end, isEndInt := asInt64(args[1])
+		if !isStartInt || !isEndInt {
+			panic(nativeError("Range bounds must be integers."))
+		}
+		return &numberRange{start: start, end: end}
+	}})
 }
 
 func (interp *Interpreter) Interpret(stmts []ast.Stmt) (result interface{}, hadRuntimeError bool) {
@@ -93,17 +134,81 @@ func (interp *Interpreter) VisitIfStmt(stmt ast.IfStmt) interface{} {
 
 func (interp *Interpreter) VisitWhileStmt(stmt ast.WhileStmt) interface{} {
 	for interp.isTruthy(interp.evaluate(stmt.Condition)) {
-		interp.execute(stmt.Body)
+		if interp.executeLoopBody(stmt.Body, interp.environment) {
+			break
+		}
+		if stmt.Increment != nil {
+			interp.evaluate(stmt.Increment)
+		}
 	}
 	return nil
 }
 
+func (interp *Interpreter) VisitForInStmt(stmt ast.ForInStmt) interface{} {
+	iterable := interp.iterate(stmt.Start, interp.evaluate(stmt.Iterable))
+	if c, ok := iterable.(closer); ok {
+		// release a generator left suspended by break, return or an error
+		defer c.close()
+	}
+	for iterable.hasNext() {
+		// each iteration gets its own binding, so closures capture the
+		// value from that iteration
+		environment := env.CreateEnvironment(interp.environment)
+		environment.Define(stmt.Name.Lexeme, iterable.next())
+		if interp.executeLoopBody(stmt.Body, environment) {
+			break
+		}
+	}
+	return nil
+}
+
+// executeLoopBody runs one iteration of a loop in environment and reports
+// whether it was terminated by a 'break'.
+func (interp *Interpreter) executeLoopBody(body ast.Stmt, environment *env.Environment) (broke bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			switch err.(type) {
+			case Break:
+				broke = true
+			case Continue:
+			default:
+				panic(err)
+			}
+		}
+	}()
+
+	interp.executeBlock([]ast.Stmt{body}, environment)
+	return false
+}
+
+func (interp *Interpreter) VisitYieldStmt(stmt ast.YieldStmt) interface{} {
+	var value interface{}
+	if stmt.Value != nil {
+		value = interp.evaluate(stmt.Value)
+	}
+	interp.generator.yield(value)
+	return nil
+}
+
+func (interp *Interpreter) VisitBreakStmt(stmt ast.BreakStmt) interface{} {
+	panic(Break{})
+}
+
+func (interp *Interpreter) VisitContinueStmt(stmt ast.ContinueStmt) interface{} {
+	panic(Continue{})
+}
+
 func (interp *Interpreter) VisitFunctionStmt(stmt ast.FunctionStmt) interface{} {
 	function := function{declaration: stmt, closure: interp.environment, isInitializer: false}
 	interp.environment.Define(stmt.Name.Lexeme, function)
 	return nil
 }
 
+func (interp *Interpreter) VisitFunctionExpr(expr ast.FunctionExpr) interface{} {
+	declaration := ast.FunctionStmt{Params: expr.Params, Body: expr.Body, IsGenerator: expr.IsGenerator}
+	return function{declaration: declaration, closure: interp.environment, isInitializer: false}
+}
+
 func (interp *Interpreter) VisitClassStmt(stmt ast.ClassStmt) interface{} {
 	var superclass *class
 	if stmt.Superclass != nil {
@@ -114,6 +219,15 @@ func (interp *Interpreter) VisitClassStmt(stmt ast.ClassStmt) interface{} {
 		superclass = &superclassVal
 	}
 
+	traits := make([]trait, len(stmt.Traits))
+	for i, traitExpr := range stmt.Traits {
+		traitVal, ok := interp.evaluate(traitExpr).(trait)
+		if !ok {
+			interp.error(traitExpr.Name, "Can only mix in traits.")
+		}
+		traits[i] = traitVal
+	}
+
 	interp.environment.Define(stmt.Name.Lexeme, nil)
 
 	if stmt.Superclass != nil {
@@ -122,18 +236,32 @@ func (interp *Interpreter) VisitClassStmt(stmt ast.ClassStmt) interface{} {
 	}
 
 	methods := make(map[string]function, len(stmt.Methods))
+	setters := make(map[string]function)
+	interp.mixTraits(stmt, traits, methods, setters)
 	for _, method := range stmt.Methods {
 		fn := function{
 			declaration:   method,
 			closure:       interp.environment,
 			isInitializer: method.Name.Lexeme == "init",
 		}
-		methods[method.Name.Lexeme] = fn
+		if method.IsSetter {
+			setters[method.Name.Lexeme] = fn
+		} else {
+			methods[method.Name.Lexeme] = fn
+		}
+	}
+
+	statics := make(map[string]function, len(stmt.StaticMethods))
+	for _, method := range stmt.StaticMethods {
+		statics[method.Name.Lexeme] = function{declaration: method, closure: interp.environment}
 	}
 
 	class := class{
 		name:       stmt.Name.Lexeme,
 		methods:    methods,
+		setters:    setters,
+		statics:    statics,
+		fields:     make(map[string]interface{}, len(stmt.Fields)),
 		superclass: superclass,
 	}
 
@@ -142,6 +270,31 @@ func (interp *Interpreter) VisitClassStmt(stmt ast.ClassStmt) interface{} {
 	}
 
 	interp.environment.Assign(stmt.Name.Lexeme, class)
+
+	// class fields are initialized once the class exists, so they can refer to it
+	for _, field := range stmt.Fields {
+		var val interface{}
+		if field.Initializer != nil {
+			val = interp.evaluate(field.Initializer)
+		}
+		class.fields[field.Name.Lexeme] = val
+	}
+	return nil
+}
+
+func (interp *Interpreter) VisitTraitStmt(stmt ast.TraitStmt) interface{} {
+	methods := make(map[string]function, len(stmt.Methods))
+	setters := make(map[string]function)
+	for _, method := range stmt.Methods {
+		fn := function{declaration: method, closure: interp.environment}
+		if method.IsSetter {
+			setters[method.Name.Lexeme] = fn
+		} else {
+			methods[method.Name.Lexeme] = fn
+		}
+	}
+
+	interp.environment.Define(stmt.Name.Lexeme, trait{name: stmt.Name.Lexeme, methods: methods, setters: setters})
 	return nil
 }
 
@@ -153,17 +306,100 @@ func (interp *Interpreter) VisitReturnStmt(stmt ast.ReturnStmt) interface{} {
 	panic(Return{Value: value})
 }
 
+func (interp *Interpreter) VisitImportStmt(stmt ast.ImportStmt) interface{} {
+	module := interp.imports[stmt.Keyword]
+	if module.environment == nil {
+		// each module runs once, in its own global environment, which is
+		// only cached once it has run to the end; a module whose top level
+		// throws runs again the next time it is imported
+		environment := env.CreateEnvironment(nil)
+		defineNatives(environment)
+		interp.executeBlock(module.statements, environment)
+		module.environment = environment
+	}
+	interp.environment.Define(stmt.Name.Lexeme, module)
+	return nil
+}
+
+func (interp *Interpreter) VisitThrowStmt(stmt ast.ThrowStmt) interface{} {
+	value := interp.evaluate(stmt.Value)
+	if e, ok := value.(*exception); ok {
+		panic(e.err) // rethrow, keeping the original message and line
+	}
+	panic(runtimeError{token: stmt.Keyword, message: interp.stringify(value), value: value})
+}
+
+func (interp *Interpreter) VisitTryStmt(stmt ast.TryStmt) interface{} {
+	if stmt.FinallyBody != nil {
+		defer interp.executeBlock(stmt.FinallyBody, env.CreateEnvironment(interp.environment))
+	}
+
+	interp.executeTry(stmt)
+	return nil
+}
+
+// executeTry runs the try block and, when there is a catch clause, hands it any
+// runtimeError. Return, Break and Continue are not errors and pass through.
+func (interp *Interpreter) executeTry(stmt ast.TryStmt) {
+	defer func() {
+		if err := recover(); err != nil {
+			e, ok := err.(runtimeError)
+			if !ok || stmt.CatchName == nil {
+				panic(err)
+			}
+
+			environment := env.CreateEnvironment(interp.environment)
+			environment.Define(stmt.CatchName.Lexeme, &exception{err: e})
+			interp.executeBlock(stmt.CatchBody, environment)
+		}
+	}()
+
+	interp.executeBlock(stmt.Body, env.CreateEnvironment(interp.environment))
+}
+
 func (interp *Interpreter) VisitAssignExpr(expr ast.AssignExpr) interface{} {
 	value := interp.evaluate(expr.Value)
+	interp.assignVariable(expr.Name, value)
+	return value
+}
+
+func (interp *Interpreter) assignVariable(name ast.Token, value interface{}) {
 	// interp.environment.Assign(expr.Name.Lexeme, value)
-	if distance, ok := interp.locals[expr]; ok {
-		interp.environment.AssignAt(distance, expr.Name.Lexeme, value)
+	if distance, ok := interp.locals[name]; ok {
+		interp.environment.AssignAt(distance, name.Lexeme, value)
 	} else {
-		if err := interp.globals.Assign(expr.Name.Lexeme, value); err != nil {
-			panic(err)
+		if err := interp.environment.Root().Assign(name.Lexeme, value); err != nil {
+			interp.error(name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme))
 		}
 	}
-	return value
+}
+
+// VisitCompoundExpr evaluates compound assignments and increments. The parts
+// of the target are evaluated once and reused for both the read and the write.
+func (interp *Interpreter) VisitCompoundExpr(expr ast.CompoundExpr) interface{} {
+	var old, result interface{}
+	switch target := expr.Target.(type) {
+	case ast.VariableExpr:
+		old = interp.VisitVariableExpr(target)
+		result = interp.binary(expr.Operator, old, interp.evaluate(expr.Value))
+		interp.assignVariable(target.Name, result)
+	case ast.GetExpr:
+		object := interp.evaluate(target.Object)
+		old = interp.getProperty(object, target.Name)
+		result = interp.binary(expr.Operator, old, interp.evaluate(expr.Value))
+		interp.setProperty(object, target.Name, result)
+	case ast.IndexExpr:
+		object := interp.evaluate(target.Object)
+		index := interp.evaluate(target.Index)
+		old = interp.getIndex(target.Bracket, object, index)
+		result = interp.binary(expr.Operator, old, interp.evaluate(expr.Value))
+		interp.setIndex(target.Bracket, object, index, result)
+	}
+
+	if expr.Postfix {
+		return old
+	}
+	return result
 }
 
 func (interp *Interpreter) VisitExpressionStmt(stmt ast.ExpressionStmt) interface{} {
@@ -183,8 +419,17 @@ func (interp *Interpreter) VisitUnaryExpr(expr ast.UnaryExpr) interface{} {
 
 	switch expr.Operator.TokenType {
 	case ast.TokenMinus:
+		if result, ok := interp.callOperator(expr.Operator, "__neg", right); ok {
+			return result
+		}
 		interp.checkOperands(expr.Operator, right)
-		return -right.(float64)
+		return interp.negate(right)
+	case ast.TokenTilde:
+		if result, ok := interp.callOperator(expr.Operator, "__invert", right); ok {
+			return result
+		}
+		interp.checkIntegers(expr.Operator, right)
+		return interp.invert(right)
 	case ast.TokenBang:
 		return !interp.isTruthy(right)
 	}
@@ -193,32 +438,61 @@ func (interp *Interpreter) VisitUnaryExpr(expr ast.UnaryExpr) interface{} {
 
 func (interp *Interpreter) VisitGetExpr(expr ast.GetExpr) interface{} {
 	object := interp.evaluate(expr.Object)
-	if instance, ok := object.(*instance); ok {
-		val, err := instance.Get(interp, expr.Name)
-		if err != nil {
-			panic(err)
-		}
-		return val
-	}
-	interp.error(expr.Name, "Only instances have properties.")
-	return nil
+	return interp.getProperty(object, expr.Name)
 }
 
-func (interp *Interpreter) VisitSetExpr(expr ast.SetExpr) interface{} {
-	object := interp.evaluate(expr.Object)
+func (interp *Interpreter) getProperty(object interface{}, name ast.Token) interface{} {
+	var val interface{}
+	var err error
+	switch object := object.(type) {
+	case *instance:
+		val, err = object.Get(interp, name)
+	case *list:
+		val, err = object.Get(interp, name)
+	case *dict:
+		val, err = object.Get(interp, name)
+	case *module:
+		val, err = object.Get(interp, name)
+	case *exception:
+		val, err = object.Get(interp, name)
+	case *generator:
+		val, err = object.Get(interp, name)
+	case class:
+		val, err = object.Get(interp, name)
+	default:
+		interp.error(name, "Only instances have properties.")
+	}
 
-	instance, ok := object.(*instance) //doesnt work if not pointer?
-	if !ok {
-		interp.error(expr.Name, "Only instances have fields")
+	if err != nil {
+		panic(err)
 	}
+	return val
+}
 
+func (interp *Interpreter) VisitSetExpr(expr ast.SetExpr) interface{} {
+	object := interp.evaluate(expr.Object)
 	value := interp.evaluate(expr.Value)
-	instance.set(expr.Name, value)
+	interp.setProperty(object, expr.Name, value)
 	return nil
 }
 
+func (interp *Interpreter) setProperty(object interface{}, name ast.Token, value interface{}) {
+	switch object := object.(type) {
+	case *instance: //doesnt work if not pointer?
+		if setter := object.class.findSetter(name.Lexeme); setter != nil {
+			setter.bind(object).call(interp, []interface{}{value})
+		} else {
+			object.set(name, value)
+		}
+	case class:
+		object.set(name, value)
+	default:
+		interp.error(name, "Only instances and classes have fields")
+	}
+}
+
 func (interp *Interpreter) VisitThisExpr(expr ast.ThisExpr) interface{} {
-	val, err := interp.lookupVariable(expr.Keyword, expr)
+	val, err := interp.lookupVariable(expr.Keyword)
 	if err != nil {
 		panic(err)
 	}
@@ -226,30 +500,88 @@ func (interp *Interpreter) VisitThisExpr(expr ast.ThisExpr) interface{} {
 }
 
 func (interp *Interpreter) VisitSuperExpr(expr ast.SuperExpr) interface{} {
-	distance := interp.locals[expr]
+	distance := interp.locals[expr.Keyword]
 	superclass := interp.environment.GetAt(distance, "super").(*class)
 	object := interp.environment.GetAt(distance-1, "this").(*instance)
 	method := superclass.findMethod(expr.Method.Lexeme)
 	if method == nil {
 		interp.error(expr.Method, fmt.Sprintf("Undefined property '%s'.", expr.Method.Lexeme))
 	}
+	if method.declaration.IsGetter {
+		return method.bind(object).call(interp, nil)
+	}
 	return method.bind(object)
 }
 
 func (interp *Interpreter) VisitVariableExpr(expr ast.VariableExpr) interface{} {
 	// val, err := interp.environment.Get(expr.Name.Lexeme)
-	val, err := interp.lookupVariable(expr.Name, expr)
+	val, err := interp.lookupVariable(expr.Name)
 	if err != nil {
-		panic(err)
+		interp.error(expr.Name, fmt.Sprintf("Undefined variable '%s'.", expr.Name.Lexeme))
 	}
 	return val
 }
 
-func (interp *Interpreter) lookupVariable(name ast.Token, expr ast.Expr) (interface{}, error) {
-	if distance, ok := interp.locals[expr]; ok {
+func (interp *Interpreter) lookupVariable(name ast.Token) (interface{}, error) {
+	if distance, ok := interp.locals[name]; ok {
 		return interp.environment.GetAt(distance, name.Lexeme), nil
 	}
-	return interp.globals.Get(name.Lexeme)
+	return interp.environment.Root().Get(name.Lexeme)
+}
+
+func (interp *Interpreter) VisitListExpr(expr ast.ListExpr) interface{} {
+	elements := make([]interface{}, len(expr.Elements))
+	for i, element := range expr.Elements {
+		elements[i] = interp.evaluate(element)
+	}
+	return &list{elements: elements}
+}
+
+func (interp *Interpreter) VisitIndexExpr(expr ast.IndexExpr) interface{} {
+	object := interp.evaluate(expr.Object)
+	index := interp.evaluate(expr.Index)
+	return interp.getIndex(expr.Bracket, object, index)
+}
+
+func (interp *Interpreter) getIndex(bracket ast.Token, object interface{}, index interface{}) interface{} {
+	switch object := object.(type) {
+	case *list:
+		return object.get(bracket, index)
+	case *dict:
+		return object.get(bracket, index)
+	}
+
+	interp.error(bracket, "Only lists and maps can be indexed.")
+	return nil
+}
+
+func (interp *Interpreter) VisitIndexSetExpr(expr ast.IndexSetExpr) interface{} {
+	object := interp.evaluate(expr.Object)
+	index := interp.evaluate(expr.Index)
+	value := interp.evaluate(expr.Value)
+	interp.setIndex(expr.Bracket, object, index, value)
+	return value
+}
+
+func (interp *Interpreter) setIndex(bracket ast.Token, object interface{}, index interface{}, value interface{}) {
+	switch object := object.(type) {
+	case *list:
+		object.set(bracket, index, value)
+	case *dict:
+		object.set(bracket, index, value)
+	default:
+		interp.error(bracket, "Only lists and maps can be indexed.")
+	}
+}
+
+func (interp *Interpreter) VisitMapExpr(expr ast.MapExpr) interface{} {
+	d := createDict()
+	for i := range expr.Keys {
+		key := interp.evaluate(expr.Keys[i])
+		value := interp.evaluate(expr.Values[i])
+		d.set(expr.Brace, key, value)
+	}
+	return d
 }
 
 func (interp *Interpreter) VisitCallExpr(expr ast.CallExpr) interface{} {
@@ -262,6 +594,9 @@ func (interp *Interpreter) VisitCallExpr(expr ast.CallExpr) interface{} {
 
 	fn, ok := callee.(callable)
 	if !ok {
+		if expr.Paren.TokenType == ast.TokenPipe {
+			interp.error(expr.Paren, "Right-hand side of '|>' must be a function or class.")
+		}
 		interp.error(expr.Paren, "Can only call function and classes.")
 	}
 
@@ -269,54 +604,55 @@ func (interp *Interpreter) VisitCallExpr(expr ast.CallExpr) interface{} {
 		interp.error(expr.Paren, fmt.Sprintf("Expected %d arguments but got %d.", fn.arity(), len(args)))
 	}
 
+	if n, ok := fn.(native); ok {
+		return n.callAt(interp, expr.Paren, args)
+	}
 	return fn.call(interp, args)
 }
 
 func (interp *Interpreter) VisitBinaryExpr(expr ast.BinaryExpr) interface{} {
 	left := interp.evaluate(expr.Left)
 	right := interp.evaluate(expr.Right)
+	return interp.binary(expr.Operator, left, right)
+}
 
-	switch expr.Operator.TokenType {
+func (interp *Interpreter) binary(operator ast.Token, left interface{}, right interface{}) interface{} {
+	if result, ok := interp.callOperator(operator, binaryOperatorMethods[operator.TokenType], left, right); ok {
+		if operator.TokenType == ast.TokenBangEqual {
+			return !interp.isTruthy(result)
+		}
+		return result
+	}
+
+	switch operator.TokenType {
 	// arithmetic
 	case ast.TokenPlus:
-		_, isLeftFloat := left.(float64)
-		_, isRightFloat := right.(float64)
-		if isLeftFloat && isRightFloat {
-			return left.(float64) + right.(float64)
+		if isNumber(left) && isNumber(right) {
+			return interp.arithmetic(operator, left, right)
 		}
 		_, isLeftString := left.(string)
 		_, isRightString := right.(string)
 		if isLeftString && isRightString {
 			return left.(string) + right.(string)
 		}
-		interp.error(expr.Operator, "Operands must be numbers or strings")
-	case ast.TokenMinus:
-		interp.checkOperands(expr.Operator, left, right)
-		return left.(float64) - right.(float64)
-	case ast.TokenStar:
-		interp.checkOperands(expr.Operator, left, right)
-		return left.(float64) * right.(float64)
-	case ast.TokenSlash:
-		interp.checkOperands(expr.Operator, left, right)
-		return left.(float64) / right.(float64)
+		interp.error(operator, "Operands must be numbers or strings")
+	case ast.TokenMinus, ast.TokenStar, ast.TokenSlash, ast.TokenPercent, ast.TokenTildeSlash, ast.TokenStarStar:
+		interp.checkOperands(operator, left, right)
+		return interp.arithmetic(operator, left, right)
+
+	// bitwise
+	case ast.TokenAmpersand, ast.TokenBar, ast.TokenCaret, ast.TokenLessLess, ast.TokenGreaterGreater:
+		interp.checkIntegers(operator, left, right)
+		return interp.bitwise(operator, left, right)
 
 	// logical
-	case ast.TokenGreater:
-		interp.checkOperands(expr.Operator, left, right)
-		return left.(float64) > right.(float64)
-	case ast.TokenGreaterEqual:
-		interp.checkOperands(expr.Operator, left, right)
-		return left.(float64) >= right.(float64)
-	case ast.TokenLess:
-		interp.checkOperands(expr.Operator, left, right)
-		return left.(float64) < right.(float64)
-	case ast.TokenLessEqual:
-		interp.checkOperands(expr.Operator, left, right)
-		return left.(float64) <= right.(float64)
+	case ast.TokenGreater, ast.TokenGreaterEqual, ast.TokenLess, ast.TokenLessEqual:
+		interp.checkOperands(operator, left, right)
+		return interp.comparison(operator, left, right)
 	case ast.TokenEqualEqual:
-		return left == right
+		return interp.isEqual(left, right)
 	case ast.TokenBangEqual:
-		return left != right
+		return !interp.isEqual(left, right)
 	}
 
 	return nil
@@ -337,6 +673,21 @@ func (interp *Interpreter) VisitLogicalExpr(expr ast.LogicalExpr) interface{} {
 	return interp.evaluate(expr.Right)
 }
 
+func (interp *Interpreter) VisitConditionalExpr(expr ast.ConditionalExpr) interface{} {
+	if interp.isTruthy(interp.evaluate(expr.Condition)) {
+		return interp.evaluate(expr.ThenBranch)
+	}
+	return interp.evaluate(expr.ElseBranch)
+}
+
+func (interp *Interpreter) VisitInterpolationExpr(expr ast.InterpolationExpr) interface{} {
+	var builder strings.Builder
+	for _, part := range expr.Parts {
+		builder.WriteString(interp.stringify(interp.evaluate(part)))
+	}
+	return builder.String()
+}
+
 func (interp *Interpreter) executeBlock(statements []ast.Stmt, env *env.Environment) {
 	previous := interp.environment
 	defer func() {
@@ -351,7 +702,7 @@ func (interp *Interpreter) executeBlock(statements []ast.Stmt, env *env.Environm
 
 func (interp *Interpreter) checkOperands(operator ast.Token, operands ...interface{}) {
 	for _, operand := range operands {
-		if _, ok := operand.(float64); !ok {
+		if !isNumber(operand) {
 			panic(runtimeError{token: operator, message: "Operand must be number"})
 		}
 	}
@@ -367,15 +718,101 @@ func (interp *Interpreter) isTruthy(val interface{}) bool {
 	return true
 }
 
+// isEqual compares lists and maps structurally, numbers by value and
+// everything else by identity.
+func (interp *Interpreter) isEqual(a interface{}, b interface{}) bool {
+	switch a := a.(type) {
+	case *list:
+		b, ok := b.(*list)
+		if !ok || len(a.elements) != len(b.elements) {
+			return false
+		}
+		for i := range a.elements {
+			if !interp.isEqual(a.elements[i], b.elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *dict:
+		b, ok := b.(*dict)
+		if !ok || len(a.keys) != len(b.keys) {
+			return false
+		}
+		for _, key := range a.keys {
+			value, ok := b.values[key]
+			if !ok || !interp.isEqual(a.values[key], value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if isNumber(a) && isNumber(b) {
+		return numbersEqual(a, b)
+	}
+	return a == b
+}
+
+// Stringify converts a value to the text 'print' would show for it.
+func (interp *Interpreter) Stringify(value interface{}) string {
+	return interp.stringify(value)
+}
+
 func (interp *Interpreter) stringify(value interface{}) string {
-	if value == nil {
+	switch value := value.(type) {
+	case nil:
 		return "nil"
+	case *instance:
+		// a toString that takes parameters can't be called here, so the
+		// instance prints as if it had none
+		method := value.class.findMethod("toString")
+		if method == nil || method.arity() != 0 || interp.stringifying[value] {
+			return value.String()
+		}
+		interp.stringifying[value] = true
+		defer delete(interp.stringifying, value)
+		return interp.stringify(method.bind(value).call(interp, nil))
+	case *list:
+		if interp.stringifying[value] {
+			return "[...]"
+		}
+		interp.stringifying[value] = true
+		defer delete(interp.stringifying, value)
+
+		elements := make([]string, len(value.elements))
+		for i, element := range value.elements {
+			elements[i] = interp.stringify(element)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *dict:
+		if interp.stringifying[value] {
+			return "{...}"
+		}
+		interp.stringifying[value] = true
+		defer delete(interp.stringifying, value)
+
+		entries := make([]string, len(value.keys))
+		for i, key := range value.keys {
+			entries[i] = interp.stringify(key) + ": " + interp.stringify(value.values[key])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
 	}
 	return fmt.Sprint(value)
 }
 
-func (interp *Interpreter) Resolve(expr ast.Expr, depth int) {
-	interp.locals[expr] = depth
+func (interp *Interpreter) Resolve(name ast.Token, depth int) {
+	interp.locals[name] = depth
+}
+
+// AddModule registers the resolved statements of the module at path.
+func (interp *Interpreter) AddModule(path string, name string, statements []ast.Stmt) {
+	interp.modules[path] = &module{name: name, statements: statements}
+}
+
+// ResolveImport records which module the import statement with the given
+// keyword refers to.
+func (interp *Interpreter) ResolveImport(keyword ast.Token, path string) {
+	interp.imports[keyword] = interp.modules[path]
 }
 
 func (interp *Interpreter) error(token ast.Token, message string) {
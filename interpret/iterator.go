@@ -0,0 +1,152 @@
+package interpret
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/Pra1tik/golox/ast"
+)
+
+// iterator steps through the values a for-in loop binds its variable to.
+type iterator interface {
+	hasNext() bool
+	next() interface{}
+}
+
+// closer is implemented by iterators holding resources that must be released
+// when a loop stops before reaching the end.
+type closer interface {
+	close()
+}
+
+// iterate returns an iterator over value, which must be a list, string, map,
+// range, generator or an instance whose class defines iterator(). Errors are
+// reported at token.
+func (interp *Interpreter) iterate(token ast.Token, value interface{}) iterator {
+	switch value := value.(type) {
+	case *list:
+		return &listIterator{elements: value}
+	case string:
+		return &stringIterator{text: value}
+	case *dict:
+		keys := make([]interface{}, len(value.keys))
+		for i, key := range value.keys {
+			keys[i] = publicKey(key)
+		}
+		return &listIterator{elements: &list{elements: keys}}
+	case *numberRange:
+		return &rangeIterator{current: value.start, end: value.end}
+	case *generator:
+		if value.running {
+			interp.error(token, "Generator is already running.")
+		}
+		return value
+	case *instance:
+		if value.class.findMethod("iterator") != nil {
+			it := interp.callMethod(token, value, "iterator")
+			if g, ok := it.(*generator); ok && g.running {
+				interp.error(token, "Generator is already running.")
+			}
+			return &protocolIterator{interp: interp, token: token, iterator: it}
+		}
+	}
+
+	interp.error(token, "Can only iterate over lists, strings, maps, ranges, generators and instances with an iterator() method.")
+	return nil
+}
+
+// callMethod calls the parameterless method name on object.
+func (interp *Interpreter) callMethod(token ast.Token, object interface{}, name string) interface{} {
+	method := interp.getProperty(object, ast.Token{TokenType: ast.TokenIdentifier, Lexeme: name, Line: token.Line, File: token.File})
+	fn, ok := method.(callable)
+	if !ok || fn.arity() != 0 {
+		interp.error(token, fmt.Sprintf("'%s' must be a method with no parameters.", name))
+	}
+	if n, ok := fn.(native); ok {
+		return n.callAt(interp, token, nil)
+	}
+	return fn.call(interp, nil)
+}
+
+// listIterator reads the list as it goes, so elements pushed during the loop
+// are visited too.
+type listIterator struct {
+	elements *list
+	index    int
+}
+
+func (it *listIterator) hasNext() bool {
+	return it.index < len(it.elements.elements)
+}
+
+func (it *listIterator) next() interface{} {
+	element := it.elements.elements[it.index]
+	it.index++
+	return element
+}
+
+// stringIterator yields each character of a string as a string.
+type stringIterator struct {
+	text   string
+	offset int
+}
+
+func (it *stringIterator) hasNext() bool {
+	return it.offset < len(it.text)
+}
+
+func (it *stringIterator) next() interface{} {
+	_, size := utf8.DecodeRuneInString(it.text[it.offset:])
+	char := it.text[it.offset : it.offset+size]
+	it.offset += size
+	return char
+}
+
+type rangeIterator struct {
+	current int64
+	end     int64
+}
+
+func (it *rangeIterator) hasNext() bool {
+	return it.current < it.end
+}
+
+func (it *rangeIterator) next() interface{} {
+	value := it.current
+	it.current++
+	return value
+}
+
+// protocolIterator drives an object returned by a user-defined iterator()
+// method through its hasNext() and next() methods.
+type protocolIterator struct {
+	interp   *Interpreter
+	token    ast.Token
+	iterator interface{}
+}
+
+func (it *protocolIterator) hasNext() bool {
+	return it.interp.isTruthy(it.interp.callMethod(it.token, it.iterator, "hasNext"))
+}
+
+func (it *protocolIterator) next() interface{} {
+	return it.interp.callMethod(it.token, it.iterator, "next")
+}
+
+// close closes the object returned by iterator() when it is a generator.
+func (it *protocolIterator) close() {
+	if g, ok := it.iterator.(*generator); ok {
+		g.close()
+	}
+}
+
+// numberRange is the half-open range of integers [start, end) created by the
+// native range().
+type numberRange struct {
+	start int64
+	end   int64
+}
+
+func (r *numberRange) String() string {
+	return fmt.Sprintf("<range %d..%d>", r.start, r.end)
+}
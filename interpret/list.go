@@ -0,0 +1,73 @@
+package interpret
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/Pra1tik/golox/ast"
+)
+
+type list struct {
+	elements []interface{}
+}
+
+func (l *list) Get(interpreter *Interpreter, name ast.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "length":
+		return native{params: 0, fn: func(_ *Interpreter, _ []interface{}) interface{} {
+			return int64(len(l.elements))
+		}}, nil
+	case "push":
+		return native{params: 1, fn: func(_ *Interpreter, args []interface{}) interface{} {
+			l.elements = append(l.elements, args[0])
+			return nil
+		}}, nil
+	case "pop":
+		return native{params: 0, fn: func(_ *Interpreter, _ []interface{}) interface{} {
+			if len(l.elements) == 0 {
+				panic(runtimeError{token: name, message: "Can't pop from an empty list."})
+			}
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last
+		}}, nil
+	}
+
+	return nil, runtimeError{token: name, message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+}
+
+func (l *list) get(bracket ast.Token, index interface{}) interface{} {
+	return l.elements[l.index(bracket, index)]
+}
+
+func (l *list) set(bracket ast.Token, index interface{}, value interface{}) {
+	l.elements[l.index(bracket, index)] = value
+}
+
+// index accepts integers and floats with an integral value, such as the
+// result of dividing two integers.
+func (l *list) index(bracket ast.Token, index interface{}) int {
+	var i int64
+	switch index := index.(type) {
+	case int64:
+		i = index
+	case float64:
+		if index != math.Trunc(index) {
+			panic(runtimeError{token: bracket, message: "List index must be an integer."})
+		}
+		if index < 0 || index >= float64(len(l.elements)) {
+			panic(runtimeError{token: bracket, message: fmt.Sprintf("List index %s out of range for length %d.", fmt.Sprint(index), len(l.elements))})
+		}
+		i = int64(index)
+	case *big.Int:
+		panic(runtimeError{token: bracket, message: fmt.Sprintf("List index %s out of range for length %d.", index.String(), len(l.elements))})
+	default:
+		panic(runtimeError{token: bracket, message: "List index must be an integer."})
+	}
+
+	if i < 0 || i >= int64(len(l.elements)) {
+		panic(runtimeError{token: bracket, message: fmt.Sprintf("List index %d out of range for length %d.", i, len(l.elements))})
+	}
+	return int(i)
+}
@@ -0,0 +1,26 @@
+package interpret
+
+import (
+	"fmt"
+
+	"github.com/Pra1tik/golox/ast"
+	env "github.com/Pra1tik/golox/environment"
+)
+
+type module struct {
+	name        string
+	statements  []ast.Stmt
+	environment *env.Environment // nil until the module has been executed
+}
+
+func (m *module) Get(interpreter *Interpreter, name ast.Token) (interface{}, error) {
+	val, err := m.environment.Get(name.Lexeme)
+	if err != nil {
+		return nil, runtimeError{token: name, message: fmt.Sprintf("Undefined property '%s' in module '%s'.", name.Lexeme, m.name)}
+	}
+	return val, nil
+}
+
+func (m *module) String() string {
+	return "<module " + m.name + ">"
+}
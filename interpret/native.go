@@ -0,0 +1,39 @@
+package interpret
+
+import "github.com/Pra1tik/golox/ast"
+
+// nativeError is panicked by a native function to report a runtime error at
+// the call site.
+type nativeError string
+
+type native struct {
+	params int
+	fn     func(interp *Interpreter, args []interface{}) interface{}
+}
+
+func (n native) arity() int {
+	return n.params
+}
+
+func (n native) call(interp *Interpreter, args []interface{}) interface{} {
+	return n.fn(interp, args)
+}
+
+// callAt calls the native, turning a nativeError into a runtime error at
+// paren.
+func (n native) callAt(interp *Interpreter, paren ast.Token, args []interface{}) interface{} {
+	defer func() {
+		if err := recover(); err != nil {
+			if message, ok := err.(nativeError); ok {
+				panic(runtimeError{token: paren, message: string(message)})
+			}
+			panic(err)
+		}
+	}()
+
+	return n.call(interp, args)
+}
+
+func (n native) String() string {
+	return "<native fn>"
+}
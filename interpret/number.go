@@ -0,0 +1,352 @@
+package interpret
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/Pra1tik/golox/ast"
+)
+
+// Numbers are int64 when exact and float64 otherwise. Arithmetic on two
+// integers stays exact, except for '/' which always divides as floats; any
+// float operand promotes the operation to float64. Integer results that do
+// not fit in an int64 are promoted to *big.Int, and big results that fit are
+// demoted again, so a given integer value always has a single representation.
+// '~/' rounds the quotient down and '%' takes the sign of the divisor, so that
+// a == (a ~/ b) * b + a % b.
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case int64, *big.Int, float64:
+		return true
+	}
+	return false
+}
+
+func isInteger(value interface{}) bool {
+	switch value.(type) {
+	case int64, *big.Int:
+		return true
+	}
+	return false
+}
+
+func toFloat(value interface{}) float64 {
+	switch value := value.(type) {
+	case int64:
+		return float64(value)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(value).Float64()
+		return f
+	case float64:
+		return value
+	}
+	return math.NaN()
+}
+
+func toBig(value interface{}) *big.Int {
+	if i, ok := value.(int64); ok {
+		return big.NewInt(i)
+	}
+	return value.(*big.Int)
+}
+
+// asInt64 converts an int64, or a float64 with an integral value in range,
+// to an int64.
+func asInt64(value interface{}) (int64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return value, true
+	case float64:
+		if value == math.Trunc(value) && value >= math.MinInt64 && value < math.MaxInt64 {
+			return int64(value), true
+		}
+	}
+	return 0, false
+}
+
+// normalize demotes a big integer to an int64 when it fits.
+func normalize(value *big.Int) interface{} {
+	if value.IsInt64() {
+		return value.Int64()
+	}
+	return value
+}
+
+func (interp *Interpreter) arithmetic(operator ast.Token, left interface{}, right interface{}) interface{} {
+	if isInteger(left) && isInteger(right) {
+		l, isLeftInt := left.(int64)
+		r, isRightInt := right.(int64)
+		if isLeftInt && isRightInt {
+			if result, ok := interp.integerArithmetic(operator, l, r); ok {
+				return result
+			}
+		}
+		return interp.bigArithmetic(operator, toBig(left), toBig(right))
+	}
+	return interp.floatArithmetic(operator, toFloat(left), toFloat(right))
+}
+
+// integerArithmetic reports false when the result overflows an int64.
+func (interp *Interpreter) integerArithmetic(operator ast.Token, left int64, right int64) (interface{}, bool) {
+	switch operator.TokenType {
+	case ast.TokenPlus:
+		result := left + right
+		return result, (result > left) == (right > 0)
+	case ast.TokenMinus:
+		result := left - right
+		return result, (result < left) == (right > 0)
+	case ast.TokenStar:
+		return multiply(left, right)
+	case ast.TokenSlash:
+		return float64(left) / float64(right), true
+	case ast.TokenPercent:
+		interp.checkDivisor(operator, right)
+		remainder := left % right
+		if remainder != 0 && (remainder < 0) != (right < 0) {
+			remainder += right
+		}
+		return remainder, true
+	case ast.TokenTildeSlash:
+		interp.checkDivisor(operator, right)
+		if left == math.MinInt64 && right == -1 {
+			return nil, false
+		}
+		quotient := left / right
+		if (left%right != 0) && ((left < 0) != (right < 0)) {
+			quotient--
+		}
+		return quotient, true
+	case ast.TokenStarStar:
+		if right < 0 {
+			return math.Pow(float64(left), float64(right)), true
+		}
+		result := int64(1)
+		for base := left; right > 0; right >>= 1 {
+			var ok bool
+			if right&1 == 1 {
+				if result, ok = multiply(result, base); !ok {
+					return nil, false
+				}
+			}
+			if right > 1 {
+				if base, ok = multiply(base, base); !ok {
+					return nil, false
+				}
+			}
+		}
+		return result, true
+	}
+	return nil, true
+}
+
+func multiply(left int64, right int64) (int64, bool) {
+	if left == 0 || right == 0 {
+		return 0, true
+	}
+	result := left * right
+	if result/right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+		return 0, false
+	}
+	return result, true
+}
+
+func (interp *Interpreter) bigArithmetic(operator ast.Token, left *big.Int, right *big.Int) interface{} {
+	result := new(big.Int)
+	switch operator.TokenType {
+	case ast.TokenPlus:
+		result.Add(left, right)
+	case ast.TokenMinus:
+		result.Sub(left, right)
+	case ast.TokenStar:
+		result.Mul(left, right)
+	case ast.TokenSlash:
+		return toFloat(left) / toFloat(right)
+	case ast.TokenPercent:
+		interp.checkDivisor(operator, right)
+		result.Rem(left, right)
+		if result.Sign() != 0 && (result.Sign() < 0) != (right.Sign() < 0) {
+			result.Add(result, right)
+		}
+	case ast.TokenTildeSlash:
+		interp.checkDivisor(operator, right)
+		remainder := new(big.Int)
+		result.QuoRem(left, right, remainder)
+		if remainder.Sign() != 0 && (left.Sign() < 0) != (right.Sign() < 0) {
+			result.Sub(result, big.NewInt(1))
+		}
+	case ast.TokenStarStar:
+		if right.Sign() < 0 {
+			return math.Pow(toFloat(left), toFloat(right))
+		}
+		result.Exp(left, right, nil)
+	}
+	return normalize(result)
+}
+
+func (interp *Interpreter) floatArithmetic(operator ast.Token, left float64, right float64) interface{} {
+	switch operator.TokenType {
+	case ast.TokenPlus:
+		return left + right
+	case ast.TokenMinus:
+		return left - right
+	case ast.TokenStar:
+		return left * right
+	case ast.TokenSlash:
+		return left / right
+	case ast.TokenPercent:
+		interp.checkDivisor(operator, right)
+		remainder := math.Mod(left, right)
+		if remainder != 0 && (remainder < 0) != (right < 0) {
+			remainder += right
+		}
+		return remainder
+	case ast.TokenTildeSlash:
+		interp.checkDivisor(operator, right)
+		return math.Floor(left / right)
+	case ast.TokenStarStar:
+		return math.Pow(left, right)
+	}
+	return nil
+}
+
+// compareNumbers returns -1, 0 or +1 as left is less than, equal to or
+// greater than right. Integers are compared exactly.
+func compareNumbers(left interface{}, right interface{}) int {
+	if isInteger(left) && isInteger(right) {
+		l, isLeftInt := left.(int64)
+		r, isRightInt := right.(int64)
+		if !isLeftInt || !isRightInt {
+			return toBig(left).Cmp(toBig(right))
+		}
+		switch {
+		case l < r:
+			return -1
+		case l > r:
+			return 1
+		}
+		return 0
+	}
+
+	l, r := toFloat(left), toFloat(right)
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+func (interp *Interpreter) comparison(operator ast.Token, left interface{}, right interface{}) bool {
+	if math.IsNaN(toFloat(left)) || math.IsNaN(toFloat(right)) {
+		return false
+	}
+
+	switch operator.TokenType {
+	case ast.TokenGreater:
+		return compareNumbers(left, right) > 0
+	case ast.TokenGreaterEqual:
+		return compareNumbers(left, right) >= 0
+	case ast.TokenLess:
+		return compareNumbers(left, right) < 0
+	case ast.TokenLessEqual:
+		return compareNumbers(left, right) <= 0
+	}
+	return false
+}
+
+func numbersEqual(left interface{}, right interface{}) bool {
+	if math.IsNaN(toFloat(left)) || math.IsNaN(toFloat(right)) {
+		return false
+	}
+	return compareNumbers(left, right) == 0
+}
+
+func (interp *Interpreter) negate(value interface{}) interface{} {
+	switch value := value.(type) {
+	case int64:
+		if value == math.MinInt64 {
+			return new(big.Int).Neg(big.NewInt(value))
+		}
+		return -value
+	case *big.Int:
+		return normalize(new(big.Int).Neg(value))
+	}
+	return -value.(float64)
+}
+
+func (interp *Interpreter) invert(value interface{}) interface{} {
+	if i, ok := value.(int64); ok {
+		return ^i
+	}
+	return normalize(new(big.Int).Not(value.(*big.Int)))
+}
+
+func (interp *Interpreter) bitwise(operator ast.Token, left interface{}, right interface{}) interface{} {
+	switch operator.TokenType {
+	case ast.TokenLessLess, ast.TokenGreaterGreater:
+		return interp.shift(operator, left, right)
+	}
+
+	l, isLeftInt := left.(int64)
+	r, isRightInt := right.(int64)
+	if isLeftInt && isRightInt {
+		switch operator.TokenType {
+		case ast.TokenAmpersand:
+			return l & r
+		case ast.TokenBar:
+			return l | r
+		case ast.TokenCaret:
+			return l ^ r
+		}
+	}
+
+	result := new(big.Int)
+	switch operator.TokenType {
+	case ast.TokenAmpersand:
+		result.And(toBig(left), toBig(right))
+	case ast.TokenBar:
+		result.Or(toBig(left), toBig(right))
+	case ast.TokenCaret:
+		result.Xor(toBig(left), toBig(right))
+	}
+	return normalize(result)
+}
+
+func (interp *Interpreter) shift(operator ast.Token, left interface{}, right interface{}) interface{} {
+	count, ok := right.(int64)
+	if !ok {
+		interp.error(operator, "Shift count too large.")
+	}
+	if count < 0 {
+		interp.error(operator, "Shift count must not be negative.")
+	}
+
+	l, isLeftInt := left.(int64)
+	if operator.TokenType == ast.TokenGreaterGreater {
+		if isLeftInt {
+			return l >> count
+		}
+		return normalize(new(big.Int).Rsh(toBig(left), uint(count)))
+	}
+
+	if isLeftInt && count < 63 && (l<<count)>>count == l {
+		return l << count
+	}
+	return normalize(new(big.Int).Lsh(toBig(left), uint(count)))
+}
+
+func (interp *Interpreter) checkIntegers(operator ast.Token, operands ...interface{}) {
+	for _, operand := range operands {
+		if !isInteger(operand) {
+			panic(runtimeError{token: operator, message: "Operand must be integer"})
+		}
+	}
+}
+
+func (interp *Interpreter) checkDivisor(operator ast.Token, divisor interface{}) {
+	if isNumber(divisor) && toFloat(divisor) == 0 {
+		panic(runtimeError{token: operator, message: "Division by zero."})
+	}
+}
@@ -0,0 +1,49 @@
+package interpret
+
+import (
+	"fmt"
+
+	"github.com/Pra1tik/golox/ast"
+)
+
+// binaryOperatorMethods names the methods a class defines to overload binary
+// operators for its instances. '!=' negates the result of '__eq'.
+var binaryOperatorMethods = map[ast.TokenType]string{
+	ast.TokenPlus:           "__add",
+	ast.TokenMinus:          "__sub",
+	ast.TokenStar:           "__mul",
+	ast.TokenSlash:          "__div",
+	ast.TokenPercent:        "__mod",
+	ast.TokenStarStar:       "__pow",
+	ast.TokenTildeSlash:     "__floordiv",
+	ast.TokenAmpersand:      "__and",
+	ast.TokenBar:            "__or",
+	ast.TokenCaret:          "__xor",
+	ast.TokenLessLess:       "__shl",
+	ast.TokenGreaterGreater: "__shr",
+	ast.TokenLess:           "__lt",
+	ast.TokenLessEqual:      "__le",
+	ast.TokenGreater:        "__gt",
+	ast.TokenGreaterEqual:   "__ge",
+	ast.TokenEqualEqual:     "__eq",
+	ast.TokenBangEqual:      "__eq",
+}
+
+// callOperator invokes the operator method name on operand if it is an
+// instance whose class defines it, and reports whether it did.
+func (interp *Interpreter) callOperator(operator ast.Token, name string, operand interface{}, args ...interface{}) (interface{}, bool) {
+	in, ok := operand.(*instance)
+	if !ok {
+		return nil, false
+	}
+
+	method := in.class.findMethod(name)
+	if method == nil {
+		return nil, false
+	}
+
+	if method.arity() != len(args) {
+		interp.error(operator, fmt.Sprintf("Operator method '%s' must take %d parameter(s).", name, len(args)))
+	}
+	return method.bind(in).call(interp, args), true
+}
@@ -0,0 +1,52 @@
+package interpret
+
+import (
+	"fmt"
+
+	"github.com/Pra1tik/golox/ast"
+)
+
+type trait struct {
+	name    string
+	methods map[string]function
+	setters map[string]function
+}
+
+func (t trait) String() string {
+	return t.name
+}
+
+// mixTraits copies the methods of traits into the method tables of the class
+// declared by stmt. Methods the class declares itself take precedence; the
+// same method coming from two traits is an error.
+func (interp *Interpreter) mixTraits(stmt ast.ClassStmt, traits []trait, methods map[string]function, setters map[string]function) {
+	ownMethods := make(map[string]bool, len(stmt.Methods))
+	ownSetters := make(map[string]bool)
+	for _, method := range stmt.Methods {
+		if method.IsSetter {
+			ownSetters[method.Name.Lexeme] = true
+		} else {
+			ownMethods[method.Name.Lexeme] = true
+		}
+	}
+
+	mix := func(index int, from map[string]function, own map[string]bool, providers map[string]string, table map[string]function) {
+		for name, fn := range from {
+			if own[name] {
+				continue
+			}
+			if provider, ok := providers[name]; ok {
+				interp.error(stmt.Traits[index].Name, fmt.Sprintf("Method '%s' is defined by both traits '%s' and '%s'.", name, provider, traits[index].name))
+			}
+			providers[name] = traits[index].name
+			table[name] = fn
+		}
+	}
+
+	methodProviders := make(map[string]string)
+	setterProviders := make(map[string]string)
+	for i, t := range traits {
+		mix(i, t.methods, ownMethods, methodProviders, methods)
+		mix(i, t.setters, ownSetters, setterProviders, setters)
+	}
+}
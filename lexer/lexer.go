@@ -3,7 +3,11 @@ package lexer
 import (
 	"fmt"
 	"io"
+	"math/big"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/Pra1tik/golox/ast"
 )
@@ -12,23 +16,38 @@ type Scanner struct {
 	start   int
 	current int
 	line    int
-	source  string
-	tokens  []ast.Token
-	stdErr  io.Writer
+	// offset of the first character of the current line
+	lineStart int
+	source    string
+	file      string
+	tokens    []ast.Token
+	stdErr    io.Writer
+	hadError  bool
+
+	// brace depth of each "${" currently open, innermost last
+	interpolations []int
 }
 
 func CreateScanner(source string, stdErr io.Writer) *Scanner {
-	return &Scanner{source: source, stdErr: stdErr}
+	return CreateFileScanner("", source, stdErr)
 }
 
-func (s *Scanner) ScanTokens() []ast.Token {
+// CreateFileScanner creates a scanner whose tokens record the file they came
+// from, which imports are resolved relative to.
+func CreateFileScanner(file string, source string, stdErr io.Writer) *Scanner {
+	return &Scanner{source: source, file: file, line: 1, stdErr: stdErr}
+}
+
+// ScanTokens scans the whole source and reports whether any lexical errors
+// were found, in which case the tokens shouldn't be parsed.
+func (s *Scanner) ScanTokens() ([]ast.Token, bool) {
 	for !s.isAtEnd() {
 		s.start = s.current
 		s.scanToken()
 	}
 
-	s.tokens = append(s.tokens, ast.Token{TokenType: ast.TokenEof, Line: s.line})
-	return s.tokens
+	s.tokens = append(s.tokens, ast.Token{TokenType: ast.TokenEof, Line: s.line, File: s.file})
+	return s.tokens, s.hadError
 }
 
 func (s *Scanner) isAtEnd() bool {
@@ -43,21 +62,84 @@ func (s *Scanner) scanToken() {
 	case ')':
 		s.addToken(ast.TokenRightParen)
 	case '{':
+		if len(s.interpolations) > 0 {
+			s.interpolations[len(s.interpolations)-1]++
+		}
 		s.addToken(ast.TokenLeftBrace)
 	case '}':
+		if len(s.interpolations) > 0 {
+			top := len(s.interpolations) - 1
+			s.interpolations[top]--
+			if s.interpolations[top] == 0 {
+				// the '}' closing "${" resumes the enclosing string literal
+				s.interpolations = s.interpolations[:top]
+				s.string()
+				break
+			}
+		}
 		s.addToken(ast.TokenRightBrace)
+	case '[':
+		s.addToken(ast.TokenLeftBracket)
+	case ']':
+		s.addToken(ast.TokenRightBracket)
 	case ',':
 		s.addToken(ast.TokenComma)
 	case '.':
 		s.addToken(ast.TokenDot)
 	case '-':
-		s.addToken(ast.TokenMinus)
+		var tokenType ast.TokenType
+		if s.match('-') {
+			tokenType = ast.TokenMinusMinus
+		} else if s.match('=') {
+			tokenType = ast.TokenMinusEqual
+		} else {
+			tokenType = ast.TokenMinus
+		}
+		s.addToken(tokenType)
 	case '+':
-		s.addToken(ast.TokenPlus)
+		var tokenType ast.TokenType
+		if s.match('+') {
+			tokenType = ast.TokenPlusPlus
+		} else if s.match('=') {
+			tokenType = ast.TokenPlusEqual
+		} else {
+			tokenType = ast.TokenPlus
+		}
+		s.addToken(tokenType)
 	case ';':
 		s.addToken(ast.TokenSemicolon)
+	case ':':
+		s.addToken(ast.TokenColon)
+	case '?':
+		s.addToken(ast.TokenQuestionMark)
 	case '*':
-		s.addToken(ast.TokenStar)
+		var tokenType ast.TokenType
+		if s.match('*') {
+			tokenType = ast.TokenStarStar
+		} else if s.match('=') {
+			tokenType = ast.TokenStarEqual
+		} else {
+			tokenType = ast.TokenStar
+		}
+		s.addToken(tokenType)
+	case '%':
+		s.addToken(ast.TokenPercent)
+	case '~':
+		if s.match('/') {
+			s.addToken(ast.TokenTildeSlash)
+		} else {
+			s.addToken(ast.TokenTilde)
+		}
+	case '|':
+		if s.match('>') {
+			s.addToken(ast.TokenPipe)
+		} else {
+			s.addToken(ast.TokenBar)
+		}
+	case '&':
+		s.addToken(ast.TokenAmpersand)
+	case '^':
+		s.addToken(ast.TokenCaret)
 
 	case '!':
 		var tokenType ast.TokenType
@@ -72,6 +154,8 @@ func (s *Scanner) scanToken() {
 		var tokenType ast.TokenType
 		if s.match('=') {
 			tokenType = ast.TokenEqualEqual
+		} else if s.match('>') {
+			tokenType = ast.TokenArrow
 		} else {
 			tokenType = ast.TokenEqual
 		}
@@ -79,7 +163,9 @@ func (s *Scanner) scanToken() {
 
 	case '<':
 		var tokenType ast.TokenType
-		if s.match('=') {
+		if s.match('<') {
+			tokenType = ast.TokenLessLess
+		} else if s.match('=') {
 			tokenType = ast.TokenLessEqual
 		} else {
 			tokenType = ast.TokenLess
@@ -88,7 +174,9 @@ func (s *Scanner) scanToken() {
 
 	case '>':
 		var tokenType ast.TokenType
-		if s.match('=') {
+		if s.match('>') {
+			tokenType = ast.TokenGreaterGreater
+		} else if s.match('=') {
 			tokenType = ast.TokenGreaterEqual
 		} else {
 			tokenType = ast.TokenGreater
@@ -101,17 +189,9 @@ func (s *Scanner) scanToken() {
 				s.advance()
 			}
 		} else if s.match('*') {
-			for !s.isAtEnd() && (s.peek() != '*' && s.peekNext() != '/') {
-				s.advance()
-			}
-
-			if s.peek() != '*' || s.peekNext() != '/' {
-				s.error("Multiline comment not terminated")
-				break
-			}
-
-			s.advance()
-			s.advance()
+			s.blockComment()
+		} else if s.match('=') {
+			s.addToken(ast.TokenSlashEqual)
 		} else {
 			s.addToken(ast.TokenSlash)
 		}
@@ -121,10 +201,18 @@ func (s *Scanner) scanToken() {
 	case '\t':
 		break
 	case '\n':
-		s.line++
+		break
 
 	case '"':
-		s.string()
+		if s.peek() == '"' && s.peekNext() == '"' {
+			s.advance()
+			s.advance()
+			s.multilineString()
+		} else {
+			s.string()
+		}
+	case '`':
+		s.rawString()
 
 	default:
 		if isDigit(c) {
@@ -137,9 +225,18 @@ func (s *Scanner) scanToken() {
 	}
 }
 
+// advance consumes the next UTF-8 encoded character, keeping line and
+// lineStart current.
 func (s *Scanner) advance() rune {
-	ch := rune(s.source[s.current])
-	s.current++
+	ch, size := utf8.DecodeRuneInString(s.source[s.current:])
+	if ch == utf8.RuneError && size == 1 {
+		s.errorAt(s.current, "Invalid UTF-8 encoding.")
+	}
+	s.current += size
+	if ch == '\n' {
+		s.line++
+		s.lineStart = s.current
+	}
 	return ch
 }
 
@@ -155,17 +252,32 @@ func (s *Scanner) addTokenWithLiteral(tokenType ast.TokenType, literal interface
 		Literal:   literal,
 		Line:      s.line,
 		Start:     s.start,
+		File:      s.file,
 	}
 
 	s.tokens = append(s.tokens, token)
 }
 
+// string scans the rest of a string literal, decoding escape sequences. A
+// "${" ends the current part with a TokenInterpolation; scanning resumes at
+// the matching '}'.
 func (s *Scanner) string() {
+	var value strings.Builder
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
+		if s.peek() == '$' && s.peekNext() == '{' {
+			s.advance()
+			s.advance()
+			s.addTokenWithLiteral(ast.TokenInterpolation, value.String())
+			s.interpolations = append(s.interpolations, 1)
+			return
+		}
+
+		c := s.advance()
+		if c == '\\' && !s.isAtEnd() {
+			s.escape(&value)
+		} else {
+			value.WriteRune(c)
 		}
-		s.advance()
 	}
 
 	if s.isAtEnd() {
@@ -174,26 +286,256 @@ func (s *Scanner) string() {
 	}
 
 	s.advance()
-	value := s.source[s.start+1 : s.current-1]
-	s.addTokenWithLiteral(ast.TokenString, value)
+	s.addTokenWithLiteral(ast.TokenString, value.String())
 }
 
-func (s *Scanner) number() {
-	for isDigit(s.peek()) {
+// blockComment skips a /* */ comment, which may contain nested comments.
+func (s *Scanner) blockComment() {
+	line, column := s.line, s.column(s.start)
+	depth := 1
+	for depth > 0 && !s.isAtEnd() {
+		if s.peek() == '/' && s.peekNext() == '*' {
+			s.advance()
+			s.advance()
+			depth++
+		} else if s.peek() == '*' && s.peekNext() == '/' {
+			s.advance()
+			s.advance()
+			depth--
+		} else {
+			s.advance()
+		}
+	}
+
+	if depth > 0 {
+		s.report(line, column, "Unterminated block comment.")
+	}
+}
+
+// rawString scans a backtick-delimited string, which may span lines and has
+// no escape sequences or interpolation.
+func (s *Scanner) rawString() {
+	line, column := s.line, s.column(s.start)
+	for s.peek() != '`' && !s.isAtEnd() {
+		s.advance()
+	}
+
+	if s.isAtEnd() {
+		s.report(line, column, "Unterminated raw string.")
+		return
+	}
+
+	s.advance()
+	s.addTokenWithLiteral(ast.TokenString, s.source[s.start+1:s.current-1])
+}
+
+// multilineString scans a string delimited by triple quotes. Like a raw
+// string its contents are taken literally, except that indentation common to
+// its lines is removed along with the line breaks after the opening and
+// before the closing delimiter.
+func (s *Scanner) multilineString() {
+	line, column := s.line, s.column(s.start)
+	for !strings.HasPrefix(s.source[s.current:], `"""`) && !s.isAtEnd() {
+		s.advance()
+	}
+
+	if s.isAtEnd() {
+		s.report(line, column, "Unterminated multi-line string.")
+		return
+	}
+
+	text := s.source[s.start+3 : s.current]
+	s.advance()
+	s.advance()
+	s.advance()
+	s.addTokenWithLiteral(ast.TokenString, stripIndent(text))
+}
+
+// stripIndent drops a blank first and last line from text and removes the
+// leading whitespace shared by the remaining non-blank lines and the last
+// line. Blank lines become empty.
+func stripIndent(text string) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) > 1 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+
+	indent := -1
+	measure := func(line string) {
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || width < indent {
+			indent = width
+		}
+	}
+	if last := lines[len(lines)-1]; len(lines) > 1 && strings.TrimSpace(last) == "" {
+		measure(last)
+		lines = lines[:len(lines)-1]
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			measure(line)
+		}
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  '\000',
+	'\\': '\\',
+	'"':  '"',
+	'$':  '$',
+}
+
+// escape decodes the escape sequence after a backslash into value. A
+// backslash at the end of a line continues the string on the next line
+// without a line break.
+func (s *Scanner) escape(value *strings.Builder) {
+	// the position of the backslash, recorded before advancing in case the
+	// escape runs onto the next line
+	line, column := s.line, s.column(s.current-1)
+	c := s.advance()
+	if c == '\r' && s.peek() == '\n' {
+		c = s.advance()
+	}
+	if c == '\n' {
+		return
+	}
+	if decoded, ok := escapes[c]; ok {
+		value.WriteRune(decoded)
+		return
+	}
+	if c != 'u' {
+		s.report(line, column, fmt.Sprintf("Invalid escape sequence '\\%c'.", c))
+		return
+	}
+
+	// \u{XXXX} with one to six hex digits naming a Unicode code point
+	if !s.match('{') {
+		s.report(line, column, "Expect '{' after '\\u'.")
+		return
+	}
+	digits := s.current
+	for isDigitIn(s.peek(), 16) {
 		s.advance()
 	}
+	hex := s.source[digits:s.current]
+	if !s.match('}') || len(hex) == 0 || len(hex) > 6 {
+		s.report(line, column, "Invalid Unicode escape sequence.")
+		return
+	}
+	code, _ := strconv.ParseInt(hex, 16, 32)
+	if !utf8.ValidRune(rune(code)) {
+		s.report(line, column, fmt.Sprintf("Invalid Unicode code point '%s'.", hex))
+		return
+	}
+	value.WriteRune(rune(code))
+}
 
-	if s.peek() == '.' && isDigit((s.peekNext())) {
+// number scans a numeric literal: a decimal integer or float with an
+// optional fraction and exponent, or an integer with a 0x, 0o or 0b prefix.
+// Digits may be grouped with single underscores between them.
+func (s *Scanner) number() {
+	base := 10
+	if s.source[s.start] == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+	}
+
+	valid := true
+	isFloat := false
+	if base != 10 {
 		s.advance()
-		for isDigit(s.peek()) {
+		valid = s.digits(base)
+	} else {
+		valid = s.digits(10)
+		if s.peek() == '.' && isDigit(s.peekNext()) {
 			s.advance()
+			valid = s.digits(10) && valid
+			isFloat = true
 		}
+		if s.peek() == 'e' || s.peek() == 'E' {
+			s.advance()
+			if s.peek() == '+' || s.peek() == '-' {
+				s.advance()
+			}
+			valid = s.digits(10) && valid
+			isFloat = true
+		}
+	}
+
+	// a literal running straight into letters or digits, as in 0b102 or 12px
+	for isAlphaNumeric(s.peek()) {
+		s.advance()
+		valid = false
+	}
+
+	text := s.source[s.start:s.current]
+	if !valid {
+		s.errorAt(s.start, fmt.Sprintf("Malformed number literal '%s'.", text))
+		return
+	}
+
+	digits := strings.ReplaceAll(text, "_", "")
+	if isFloat {
+		value, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			s.errorAt(s.start, fmt.Sprintf("Number literal '%s' out of range.", text))
+			return
+		}
+		s.addTokenWithLiteral(ast.TokenNumber, value)
+		return
 	}
 
-	value, _ := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	if base != 10 {
+		digits = digits[2:]
+	}
+	// integers that don't fit in an int64 are arbitrary-precision
+	if value, err := strconv.ParseInt(digits, base, 64); err == nil {
+		s.addTokenWithLiteral(ast.TokenNumber, value)
+		return
+	}
+	value, _ := new(big.Int).SetString(digits, base)
 	s.addTokenWithLiteral(ast.TokenNumber, value)
 }
 
+// digits consumes a run of digits in the given base, reporting false if
+// there are none or if an underscore doesn't sit between two digits.
+func (s *Scanner) digits(base int) bool {
+	valid := true
+	previous := rune(s.source[s.current-1])
+	count := 0
+	if isDigitIn(previous, base) {
+		count++
+	}
+	for isDigitIn(s.peek(), base) || s.peek() == '_' {
+		c := s.advance()
+		if c == '_' {
+			valid = valid && isDigitIn(previous, base)
+		} else {
+			count++
+		}
+		previous = c
+	}
+	return valid && count > 0 && previous != '_'
+}
+
 func (s *Scanner) identifier() {
 	for isAlphaNumeric(s.peek()) {
 		s.advance()
@@ -213,11 +555,11 @@ func (s *Scanner) match(expected rune) bool {
 		return false
 	}
 
-	if rune(s.source[s.current]) != expected {
+	if s.peek() != expected {
 		return false
 	}
 
-	s.current++
+	s.advance()
 	return true
 }
 
@@ -226,25 +568,44 @@ func (s *Scanner) peek() rune {
 		return '\000'
 	}
 
-	return rune(s.source[s.current])
+	ch, _ := utf8.DecodeRuneInString(s.source[s.current:])
+	return ch
 }
 
 func (s *Scanner) peekNext() rune {
-	if s.current+1 >= len(s.source) {
+	if s.isAtEnd() {
+		return '\000'
+	}
+	_, size := utf8.DecodeRuneInString(s.source[s.current:])
+	if s.current+size >= len(s.source) {
 		return '\000'
 	}
 
-	return rune(s.source[s.current+1])
+	ch, _ := utf8.DecodeRuneInString(s.source[s.current+size:])
+	return ch
 }
 
 func isDigit(char rune) bool {
 	return char >= '0' && char <= '9'
 }
 
+func isDigitIn(char rune, base int) bool {
+	switch base {
+	case 2:
+		return char == '0' || char == '1'
+	case 8:
+		return char >= '0' && char <= '7'
+	case 16:
+		return isDigit(char) || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
+	}
+	return isDigit(char)
+}
+
 func isAlpha(char rune) bool {
 	return (char >= 'a' && char <= 'z') ||
 		(char >= 'A' && char <= 'Z') ||
-		char == '_'
+		char == '_' ||
+		(char >= utf8.RuneSelf && unicode.IsLetter(char))
 }
 
 func isAlphaNumeric(char rune) bool {
@@ -269,11 +630,39 @@ var keywords = map[string]ast.TokenType{
 	"var":    ast.TokenVar,
 	"while":  ast.TokenWhile,
 
-	// "break":    ast.TokenBreak,
-	// "continue": ast.TokenContinue,
+	"break":    ast.TokenBreak,
+	"continue": ast.TokenContinue,
 	// "type":     ast.TokenTypeType,
+	"import":  ast.TokenImport,
+	"as":      ast.TokenAs,
+	"throw":   ast.TokenThrow,
+	"try":     ast.TokenTry,
+	"catch":   ast.TokenCatch,
+	"finally": ast.TokenFinally,
+	"trait":   ast.TokenTrait,
+	"with":    ast.TokenWith,
+	"in":      ast.TokenIn,
+	"yield":   ast.TokenYield,
 }
 
 func (s *Scanner) error(msg string) {
+	s.hadError = true
 	_, _ = s.stdErr.Write([]byte(fmt.Sprintf("[line %d] Error: %s\n", s.line, msg)))
 }
+
+// errorAt reports an error at the column of offset, which must be on the
+// current line.
+func (s *Scanner) errorAt(offset int, msg string) {
+	s.report(s.line, s.column(offset), msg)
+}
+
+func (s *Scanner) report(line int, column int, msg string) {
+	s.hadError = true
+	_, _ = s.stdErr.Write([]byte(fmt.Sprintf("[line %d, column %d] Error: %s\n", line, column, msg)))
+}
+
+// column returns the 1-based column of offset, which must be on the current
+// line.
+func (s *Scanner) column(offset int) int {
+	return utf8.RuneCountInString(s.source[s.lineStart:offset]) + 1
+}
@@ -44,7 +44,7 @@ func runFile(path string) {
 	source, err := os.ReadFile(path)
 	checkError(err)
 
-	run(string(source))
+	run(path, string(source))
 	if hadError {
 		os.Exit(65)
 	}
@@ -62,16 +62,21 @@ func runPrompt() {
 		}
 
 		line := inputScanner.Text()
-		fmt.Println(run(line))
+		fmt.Println(run("", line))
 		hadError = false // mistake shouldn't kill the entire session
 	}
 }
 
-func run(source string) interface{} {
+func run(file string, source string) interface{} {
 	stdErr = os.Stderr
 	stdOut = os.Stdout
-	lexer := lexer.CreateScanner(source, stdErr)
-	tokens := lexer.ScanTokens()
+	lexer := lexer.CreateFileScanner(file, source, stdErr)
+	var tokens []ast.Token
+	tokens, hadError = lexer.ScanTokens()
+
+	if hadError {
+		return nil
+	}
 
 	// print tokens
 	// for _, token := range tokens {
@@ -97,7 +102,7 @@ func run(source string) interface{} {
 
 	var result interface{}
 	result, hadRuntimeError = interpreter.Interpret(statements)
-	return result
+	return interpreter.Stringify(result)
 }
 
 func errorFunc(line int, message string) {
@@ -105,5 +110,5 @@ func errorFunc(line int, message string) {
 }
 
 func report(line int, where string, message string) {
-	fmt.Errorf("[line %d] Error %s : %s", line, where, message)
+	fmt.Fprintf(os.Stderr, "[line %d] Error %s : %s\n", line, where, message)
 }
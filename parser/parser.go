@@ -8,14 +8,24 @@ import (
 )
 
 // program → declaration* EOF ;
-// declaration → varDecl | statement | funDecl | classDecl ;
+// declaration → varDecl | statement | funDecl | classDecl | traitDecl
+// 			   | importDecl ;
+// importDecl → "import" STRING "as" IDENTIFIER ";" ;
 // funDecl → "fun" function ;
 // function → IDENTIFIER "(" parameters? ")" block ;
 // parameters → IDENTIFIER ( "," IDENTIFIER )* ;
 // classDecl → "class" IDENTIFIER ( "<" IDENTIFIER )?
-// 				 "{" function* "}" ;
+// 				 ( "with" IDENTIFIER ( "," IDENTIFIER )* )?
+// 				 "{" ( method | "class" method | classField )* "}" ;
+// traitDecl → "trait" IDENTIFIER "{" method* "}" ;
+// method → function | getter | setter ;
+// getter → IDENTIFIER block ;
+// setter → "set" IDENTIFIER "(" IDENTIFIER ")" block ;
+// classField → "class" IDENTIFIER ( "=" expression )? ";" ;
 // statement → exprStmt | printStmt | block | ifStmt
-// 			 | whileStmt | forStmt | returnStmt ;
+// 			 | whileStmt | forStmt | returnStmt
+// 			 | breakStmt | continueStmt | throwStmt | tryStmt
+// 			 | yieldStmt ;
 // block → "{" declaration* "}" ;
 // varDecl → "var" IDENTIFIER ( "=" expression )? ";" ;
 // exprStmt → expression ";" ;
@@ -23,30 +33,55 @@ import (
 // whileStmt → "while" "(" expression ")" statement ;
 // forStmt → "for" "(" ( varDecl | exprStmt | ";" )
 //			expression? ";"
-//			expression? ")" statement ;
+//			expression? ")" statement
+//		   | "for" "(" "var" IDENTIFIER "in" expression ")" statement ;
 // ifStmt → "if" "(" expression ")" statement
 //          ( "else" statement )? ;
 // returnStmt → "return" expression? ";" ;
+// yieldStmt → "yield" expression? ";" ;
+// breakStmt → "break" ";" ;
+// continueStmt → "continue" ";" ;
+// throwStmt → "throw" expression ";" ;
+// tryStmt → "try" block ( "catch" "(" IDENTIFIER ")" block )?
+// 			 ( "finally" block )? ;
 // expression → assignment ;
-// assignment → ( call "." )? IDENTIFIER "=" assignment | logic_or ;
+// assignment → ( call "." )? IDENTIFIER "=" assignment
+// 			 | call "[" expression "]" "=" assignment
+// 			 | call ( "+=" | "-=" | "*=" | "/=" ) assignment | conditional ;
+// conditional → pipe ( "?" expression ":" conditional )? ;
+// pipe → logic_or ( "|>" logic_or )* ;
 // logic_or → logic_and ( "or" logic_and )* ;
 // logic_and → equality ( "and" equality )* ;
 // equality → comparison ( ( "!=" | "==" ) comparison )* ;
-// comparison → term ( ( ">" | ">=" | "<" | "<=" ) term )* ;
-// term → factor ( ( "-" | "+" ) factor )* ;
-// factor → unary ( ( "/" | "*" ) unary )* ;
-// unary → ( "!" | "-" ) unary | call ;
-// call → primary ( "(" arguments? ")" | "." IDENTIFIER )* ;
+// comparison → bit_or ( ( ">" | ">=" | "<" | "<=" ) bit_or )* ;
+// bit_or → bit_xor ( "|" bit_xor )* ;
+// bit_xor → bit_and ( "^" bit_and )* ;
+// bit_and → shift ( "&" shift )* ;
+// shift → term ( ( "<<" | ">>" ) term )* ;
+// term → factor ( ( "-" | "+" | "--" ) factor )* ;
+// factor → unary ( ( "/" | "*" | "%" | "~/" ) unary )* ;
+// unary → ( "!" | "-" | "~" | "++" | "--" ) unary | power ;
+// power → postfix ( "**" unary )? ;
+// postfix → call ( "++" | "--" )? ; (only when call is assignable)
+// call → primary ( "(" arguments? ")" | "." IDENTIFIER | "[" expression "]" )* ;
 // arguments → expression ( "," expression )* ;
 // primary → NUMBER | STRING | "true" | "false" | "nil" | "this"
 // 		|  "(" expression ")" | IDENTIFIER
-// 		| "super" "." IDENTIFIER ;
+// 		| "super" "." IDENTIFIER | "[" arguments? "]"
+// 		| "{" ( entry ( "," entry )* )? "}"
+// 		| ( INTERPOLATION expression )+ STRING
+// 		| "fun" "(" parameters? ")" block
+// 		| "(" parameters? ")" "=>" ( expression | block ) ;
+// entry → expression ":" expression ;
 
 type Parser struct {
 	tokens   []ast.Token
 	current  int
 	stdErr   io.Writer
 	hadError bool
+
+	// whether a 'yield' has been parsed in the current function body
+	yields bool
 }
 
 func CreateParser(tokens []ast.Token, stdErr io.Writer) *Parser {
@@ -66,12 +101,19 @@ func (p *Parser) declaration() ast.Stmt {
 	if p.match(ast.TokenVar) {
 		return p.varDeclaration()
 	}
-	if p.match(ast.TokenFun) {
+	if p.check(ast.TokenFun) && p.peekNext().TokenType == ast.TokenIdentifier {
+		p.advance()
 		return p.function("function")
 	}
 	if p.match(ast.TokenClass) {
 		return p.classDeclaration()
 	}
+	if p.match(ast.TokenTrait) {
+		return p.traitDeclaration()
+	}
+	if p.match(ast.TokenImport) {
+		return p.importDeclaration()
+	}
 	return p.statement()
 }
 
@@ -86,10 +128,45 @@ func (p *Parser) varDeclaration() ast.Stmt {
 	return ast.VarStmt{Name: var_name, Initializer: initializer}
 }
 
+func (p *Parser) importDeclaration() ast.Stmt {
+	keyword := p.previous()
+	path := p.consume(ast.TokenString, "Expect module path after 'import'.")
+	p.consume(ast.TokenAs, "Expect 'as' after module path.")
+	name := p.consume(ast.TokenIdentifier, "Expect module name after 'as'.")
+	p.consume(ast.TokenSemicolon, "Expect ';' after import.")
+	return ast.ImportStmt{Keyword: keyword, Path: path, Name: name}
+}
+
 func (p *Parser) function(kind string) ast.FunctionStmt {
 	name := p.consume(ast.TokenIdentifier, "Expect "+kind+" name.")
 
+	if kind == "method" && p.match(ast.TokenLeftBrace) {
+		// a method without a parameter list is a getter
+		body, isGenerator := p.functionBody()
+		return ast.FunctionStmt{Name: name, Body: body, IsGetter: true, IsGenerator: isGenerator}
+	}
+
 	p.consume(ast.TokenLeftParen, "Expect '(' after "+kind+" name.")
+	parameters := p.parameters()
+
+	p.consume(ast.TokenLeftBrace, "Expect '{' before "+kind+" body.")
+	body, isGenerator := p.functionBody()
+
+	return ast.FunctionStmt{Name: name, Params: parameters, Body: body, IsGenerator: isGenerator}
+}
+
+// functionBody parses the block of a function and reports whether it
+// contains a 'yield', which makes the function a generator.
+func (p *Parser) functionBody() (body []ast.Stmt, isGenerator bool) {
+	enclosing := p.yields
+	p.yields = false
+	body = p.block()
+	isGenerator = p.yields
+	p.yields = enclosing
+	return body, isGenerator
+}
+
+func (p *Parser) parameters() []ast.Token {
 	var parameters []ast.Token
 	if !p.check(ast.TokenRightParen) {
 		for {
@@ -105,11 +182,7 @@ func (p *Parser) function(kind string) ast.FunctionStmt {
 		}
 	}
 	p.consume(ast.TokenRightParen, "Expect ')' after parameters.")
-
-	p.consume(ast.TokenLeftBrace, "Expect '{' before "+kind+" body.")
-	body := p.block()
-
-	return ast.FunctionStmt{Name: name, Params: parameters, Body: body}
+	return parameters
 }
 
 func (p *Parser) classDeclaration() ast.Stmt {
@@ -121,26 +194,80 @@ func (p *Parser) classDeclaration() ast.Stmt {
 		superclass = &ast.VariableExpr{Name: p.previous()}
 	}
 
+	traits := make([]ast.VariableExpr, 0)
+	if p.match(ast.TokenWith) {
+		for {
+			p.consume(ast.TokenIdentifier, "Expect trait name.")
+			traits = append(traits, ast.VariableExpr{Name: p.previous()})
+			if !p.match(ast.TokenComma) {
+				break
+			}
+		}
+	}
+
 	p.consume(ast.TokenLeftBrace, "Expect '{' before class body.")
 
 	methods := make([]ast.FunctionStmt, 0)
+	staticMethods := make([]ast.FunctionStmt, 0)
+	fields := make([]ast.VarStmt, 0)
 	for !p.check(ast.TokenRightBrace) && !p.isAtEnd() {
-		method := p.function("method")
-		methods = append(methods, method)
+		if p.match(ast.TokenClass) {
+			if next := p.peekNext().TokenType; next == ast.TokenLeftParen || next == ast.TokenLeftBrace {
+				staticMethods = append(staticMethods, p.function("method"))
+			} else {
+				fields = append(fields, p.varDeclaration().(ast.VarStmt))
+			}
+			continue
+		}
+
+		methods = append(methods, p.method())
 	}
 
 	p.consume(ast.TokenRightBrace, "Expect '}' after class body.")
 	return ast.ClassStmt{
-		Name:       name,
-		Methods:    methods,
-		Superclass: superclass,
+		Name:          name,
+		Methods:       methods,
+		StaticMethods: staticMethods,
+		Fields:        fields,
+		Superclass:    superclass,
+		Traits:        traits,
 	}
 }
 
+func (p *Parser) traitDeclaration() ast.Stmt {
+	name := p.consume(ast.TokenIdentifier, "Expect trait name.")
+	p.consume(ast.TokenLeftBrace, "Expect '{' before trait body.")
+
+	methods := make([]ast.FunctionStmt, 0)
+	for !p.check(ast.TokenRightBrace) && !p.isAtEnd() {
+		methods = append(methods, p.method())
+	}
+
+	p.consume(ast.TokenRightBrace, "Expect '}' after trait body.")
+	return ast.TraitStmt{Name: name, Methods: methods}
+}
+
+func (p *Parser) method() ast.FunctionStmt {
+	if p.peek().Lexeme == "set" && p.peekNext().TokenType == ast.TokenIdentifier {
+		p.advance()
+		setter := p.function("setter")
+		if len(setter.Params) != 1 {
+			p.error(setter.Name, "A setter must have exactly one parameter.")
+		}
+		setter.IsSetter = true
+		return setter
+	}
+
+	return p.function("method")
+}
+
 func (p *Parser) statement() ast.Stmt {
 	if p.match(ast.TokenPrint) {
 		return p.printStatement()
 	}
+	if p.check(ast.TokenLeftBrace) && p.isMapLiteral() {
+		return p.expressionStatement()
+	}
 	if p.match(ast.TokenLeftBrace) {
 		stmt := p.block()
 		return ast.BlockStmt{Statements: stmt}
@@ -157,6 +284,28 @@ func (p *Parser) statement() ast.Stmt {
 	if p.match(ast.TokenReturn) {
 		return p.returnStatement()
 	}
+	if p.match(ast.TokenYield) {
+		return p.yieldStatement()
+	}
+	if p.match(ast.TokenThrow) {
+		keyword := p.previous()
+		value := p.expression()
+		p.consume(ast.TokenSemicolon, "Expect ';' after thrown value.")
+		return ast.ThrowStmt{Keyword: keyword, Value: value}
+	}
+	if p.match(ast.TokenTry) {
+		return p.tryStatement()
+	}
+	if p.match(ast.TokenBreak) {
+		keyword := p.previous()
+		p.consume(ast.TokenSemicolon, "Expect ';' after 'break'.")
+		return ast.BreakStmt{Keyword: keyword}
+	}
+	if p.match(ast.TokenContinue) {
+		keyword := p.previous()
+		p.consume(ast.TokenSemicolon, "Expect ';' after 'continue'.")
+		return ast.ContinueStmt{Keyword: keyword}
+	}
 	return p.expressionStatement()
 }
 
@@ -189,9 +338,25 @@ func (p *Parser) whileStatement() ast.Stmt {
 	return ast.WhileStmt{Condition: condition, Body: body}
 }
 
+func (p *Parser) forInStatement() ast.Stmt {
+	p.consume(ast.TokenVar, "Expect 'var' in for-in loop.")
+	name := p.consume(ast.TokenIdentifier, "Expect variable name.")
+	p.consume(ast.TokenIn, "Expect 'in' after loop variable.")
+	start := p.peek()
+	iterable := p.expression()
+	p.consume(ast.TokenRightParen, "Expect ')' after for-in iterable.")
+	body := p.statement()
+
+	return ast.ForInStmt{Name: name, Start: start, Iterable: iterable, Body: body}
+}
+
 func (p *Parser) forStatement() ast.Stmt {
 	p.consume(ast.TokenLeftParen, "Expect '(' after 'for'.")
 
+	if p.check(ast.TokenVar) && p.current+2 < len(p.tokens) && p.tokens[p.current+2].TokenType == ast.TokenIn {
+		return p.forInStatement()
+	}
+
 	var initializer ast.Stmt
 	if p.match(ast.TokenSemicolon) {
 		initializer = nil
@@ -214,14 +379,12 @@ func (p *Parser) forStatement() ast.Stmt {
 	p.consume(ast.TokenRightParen, "Expect ')' after for clauses.")
 	body := p.statement()
 
-	if increment != nil {
-		body = ast.BlockStmt{Statements: []ast.Stmt{body, ast.ExpressionStmt{Expr: increment}}}
-	}
-
 	if condition == nil {
 		condition = ast.LiteralExpr{Value: true}
 	}
-	body = ast.WhileStmt{Body: body, Condition: condition}
+	// the increment stays on the loop rather than the tail of the body so
+	// that 'continue' still runs it
+	body = ast.WhileStmt{Body: body, Condition: condition, Increment: increment}
 
 	if initializer != nil {
 		body = ast.BlockStmt{Statements: []ast.Stmt{initializer, body}}
@@ -230,6 +393,30 @@ func (p *Parser) forStatement() ast.Stmt {
 	return body
 }
 
+func (p *Parser) tryStatement() ast.Stmt {
+	keyword := p.previous()
+	p.consume(ast.TokenLeftBrace, "Expect '{' after 'try'.")
+	stmt := ast.TryStmt{Body: p.block()}
+
+	if p.match(ast.TokenCatch) {
+		p.consume(ast.TokenLeftParen, "Expect '(' after 'catch'.")
+		name := p.consume(ast.TokenIdentifier, "Expect exception variable name.")
+		p.consume(ast.TokenRightParen, "Expect ')' after exception variable.")
+		p.consume(ast.TokenLeftBrace, "Expect '{' before catch body.")
+		stmt.CatchName = &name
+		stmt.CatchBody = p.block()
+	}
+
+	if p.match(ast.TokenFinally) {
+		p.consume(ast.TokenLeftBrace, "Expect '{' after 'finally'.")
+		stmt.FinallyBody = p.block()
+	} else if stmt.CatchName == nil {
+		p.error(keyword, "Expect 'catch' or 'finally' after try block.")
+	}
+
+	return stmt
+}
+
 func (p *Parser) returnStatement() ast.Stmt {
 	keyword := p.previous()
 	var value ast.Expr
@@ -241,6 +428,18 @@ func (p *Parser) returnStatement() ast.Stmt {
 	return ast.ReturnStmt{Keyword: keyword, Value: value}
 }
 
+func (p *Parser) yieldStatement() ast.Stmt {
+	keyword := p.previous()
+	var value ast.Expr
+	if !p.check(ast.TokenSemicolon) {
+		value = p.expression()
+	}
+
+	p.consume(ast.TokenSemicolon, "Expect ';' after yield value.")
+	p.yields = true
+	return ast.YieldStmt{Keyword: keyword, Value: value}
+}
+
 func (p *Parser) expressionStatement() ast.Stmt {
 	expr := p.expression()
 	p.consume(ast.TokenSemicolon, "Expected token ';' after value")
@@ -262,7 +461,7 @@ func (p *Parser) block() []ast.Stmt {
 }
 
 func (p *Parser) assignment() ast.Expr {
-	expr := p.or()
+	expr := p.conditional()
 
 	if p.match(ast.TokenEqual) {
 		equals := p.previous()
@@ -276,11 +475,90 @@ func (p *Parser) assignment() ast.Expr {
 				Name:   getExpr.Name,
 				Value:  value,
 			}
+		} else if indexExpr, ok := expr.(ast.IndexExpr); ok {
+			return ast.IndexSetExpr{
+				Object:  indexExpr.Object,
+				Bracket: indexExpr.Bracket,
+				Index:   indexExpr.Index,
+				Value:   value,
+			}
 		}
 
 		p.error(equals, "Invalid assignment target.")
 	}
 
+	if p.match(ast.TokenPlusEqual, ast.TokenMinusEqual, ast.TokenStarEqual, ast.TokenSlashEqual) {
+		operator := p.previous()
+		value := p.assignment()
+		return p.compound(expr, operator, value, false)
+	}
+
+	return expr
+}
+
+var compoundOperators = map[ast.TokenType]ast.TokenType{
+	ast.TokenPlusEqual:  ast.TokenPlus,
+	ast.TokenMinusEqual: ast.TokenMinus,
+	ast.TokenStarEqual:  ast.TokenStar,
+	ast.TokenSlashEqual: ast.TokenSlash,
+	ast.TokenPlusPlus:   ast.TokenPlus,
+	ast.TokenMinusMinus: ast.TokenMinus,
+}
+
+func (p *Parser) compound(target ast.Expr, operator ast.Token, value ast.Expr, postfix bool) ast.Expr {
+	if !isAssignable(target) {
+		p.error(operator, "Invalid assignment target.")
+	}
+
+	operator.TokenType = compoundOperators[operator.TokenType]
+	return ast.CompoundExpr{Target: target, Operator: operator, Value: value, Postfix: postfix}
+}
+
+func isAssignable(expr ast.Expr) bool {
+	switch expr.(type) {
+	case ast.VariableExpr, ast.GetExpr, ast.IndexExpr:
+		return true
+	}
+	return false
+}
+
+// splitMinusMinus returns the two '-' tokens a '--' stands for when it
+// doesn't apply to something assignable, so 1--1 still means 1 - -1.
+func splitMinusMinus(token ast.Token) (ast.Token, ast.Token) {
+	first := token
+	first.TokenType, first.Lexeme = ast.TokenMinus, "-"
+	second := first
+	second.Start++
+	return first, second
+}
+
+func (p *Parser) conditional() ast.Expr {
+	expr := p.pipe()
+
+	if p.match(ast.TokenQuestionMark) {
+		thenBranch := p.expression()
+		p.consume(ast.TokenColon, "Expect ':' after then branch of conditional expression.")
+		elseBranch := p.conditional()
+		return ast.ConditionalExpr{Condition: expr, ThenBranch: thenBranch, ElseBranch: elseBranch}
+	}
+	return expr
+}
+
+// pipe lowers `x |> f` to `f(x)` and `x |> g(1)` to `g(x, 1)`. The call's
+// paren is the pipe token so runtime call errors point at it.
+func (p *Parser) pipe() ast.Expr {
+	expr := p.or()
+
+	for p.match(ast.TokenPipe) {
+		pipe := p.previous()
+		right := p.or()
+		if call, ok := right.(ast.CallExpr); ok {
+			args := append([]ast.Expr{expr}, call.Arguments...)
+			expr = ast.CallExpr{Callee: call.Callee, Paren: pipe, Arguments: args}
+		} else {
+			expr = ast.CallExpr{Callee: right, Paren: pipe, Arguments: []ast.Expr{expr}}
+		}
+	}
 	return expr
 }
 
@@ -319,9 +597,57 @@ func (p *Parser) equality() ast.Expr {
 }
 
 func (p *Parser) comparison() ast.Expr {
-	expr := p.term()
+	expr := p.bitOr()
 
 	for p.match(ast.TokenGreater, ast.TokenGreaterEqual, ast.TokenLess, ast.TokenLessEqual) {
+		operator := p.previous()
+		right := p.bitOr()
+		expr = ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr
+}
+
+func (p *Parser) bitOr() ast.Expr {
+	expr := p.bitXor()
+
+	for p.match(ast.TokenBar) {
+		operator := p.previous()
+		right := p.bitXor()
+		expr = ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr
+}
+
+func (p *Parser) bitXor() ast.Expr {
+	expr := p.bitAnd()
+
+	for p.match(ast.TokenCaret) {
+		operator := p.previous()
+		right := p.bitAnd()
+		expr = ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr
+}
+
+func (p *Parser) bitAnd() ast.Expr {
+	expr := p.shift()
+
+	for p.match(ast.TokenAmpersand) {
+		operator := p.previous()
+		right := p.shift()
+		expr = ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr
+}
+
+func (p *Parser) shift() ast.Expr {
+	expr := p.term()
+
+	for p.match(ast.TokenLessLess, ast.TokenGreaterGreater) {
 		operator := p.previous()
 		right := p.term()
 		expr = ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
@@ -333,8 +659,15 @@ func (p *Parser) comparison() ast.Expr {
 func (p *Parser) term() ast.Expr {
 	expr := p.factor()
 
-	for p.match(ast.TokenMinus, ast.TokenPlus) {
+	for p.match(ast.TokenMinus, ast.TokenPlus, ast.TokenMinusMinus) {
 		operator := p.previous()
+		if operator.TokenType == ast.TokenMinusMinus {
+			// parse the second '-' as a unary minus on the right operand
+			var minus ast.Token
+			operator, minus = splitMinusMinus(operator)
+			p.current--
+			p.tokens[p.current] = minus
+		}
 		right := p.factor()
 		expr = ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
 	}
@@ -345,7 +678,7 @@ func (p *Parser) term() ast.Expr {
 func (p *Parser) factor() ast.Expr {
 	expr := p.unary()
 
-	for p.match(ast.TokenSlash, ast.TokenStar) {
+	for p.match(ast.TokenSlash, ast.TokenStar, ast.TokenPercent, ast.TokenTildeSlash) {
 		operator := p.previous()
 		right := p.unary()
 		expr = ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
@@ -355,13 +688,45 @@ func (p *Parser) factor() ast.Expr {
 }
 
 func (p *Parser) unary() ast.Expr {
-	if p.match(ast.TokenBang, ast.TokenMinus) {
+	if p.match(ast.TokenBang, ast.TokenMinus, ast.TokenTilde) {
 		operator := p.previous()
 		right := p.unary()
 		return ast.UnaryExpr{Operator: operator, Right: right}
 	}
+	if p.match(ast.TokenPlusPlus, ast.TokenMinusMinus) {
+		operator := p.previous()
+		target := p.unary()
+		if operator.TokenType == ast.TokenMinusMinus && !isAssignable(target) {
+			first, second := splitMinusMinus(operator)
+			return ast.UnaryExpr{Operator: first, Right: ast.UnaryExpr{Operator: second, Right: target}}
+		}
+		return p.compound(target, operator, ast.LiteralExpr{Value: int64(1)}, false)
+	}
+
+	return p.power()
+}
 
-	return p.call()
+// power binds tighter than unary minus on its left, so -2 ** 2 is -4, and
+// is right-associative because its right operand is a unary.
+func (p *Parser) power() ast.Expr {
+	expr := p.postfix()
+
+	if p.match(ast.TokenStarStar) {
+		operator := p.previous()
+		right := p.unary()
+		return ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) postfix() ast.Expr {
+	expr := p.call()
+
+	// anything else followed by '--' is a subtraction of a negative number
+	if isAssignable(expr) && p.match(ast.TokenPlusPlus, ast.TokenMinusMinus) {
+		return p.compound(expr, p.previous(), ast.LiteralExpr{Value: int64(1)}, true)
+	}
+	return expr
 }
 
 func (p *Parser) call() ast.Expr {
@@ -373,6 +738,10 @@ func (p *Parser) call() ast.Expr {
 		} else if p.match(ast.TokenDot) {
 			name := p.consume(ast.TokenIdentifier, "Expect property name after '.'.")
 			expr = ast.GetExpr{Object: expr, Name: name}
+		} else if p.match(ast.TokenLeftBracket) {
+			index := p.expression()
+			bracket := p.consume(ast.TokenRightBracket, "Expect ']' after index.")
+			expr = ast.IndexExpr{Object: expr, Bracket: bracket, Index: index}
 		} else {
 			break
 		}
@@ -418,16 +787,137 @@ func (p *Parser) primary() ast.Expr {
 		p.consume(ast.TokenDot, "Expect '.' after 'super'.")
 		method := p.consume(ast.TokenIdentifier, "Expect superclass method name.")
 		return ast.SuperExpr{Keyword: keyword, Method: method}
+	case p.match(ast.TokenFun):
+		keyword := p.previous()
+		p.consume(ast.TokenLeftParen, "Expect '(' after 'fun'.")
+		parameters := p.parameters()
+		p.consume(ast.TokenLeftBrace, "Expect '{' before function body.")
+		body, isGenerator := p.functionBody()
+		return ast.FunctionExpr{Keyword: keyword, Params: parameters, Body: body, IsGenerator: isGenerator}
+	case p.check(ast.TokenLeftParen) && p.isArrowFunction():
+		return p.arrowFunction()
 	case p.match(ast.TokenLeftParen):
 		expr := p.expression()
 		p.consume(ast.TokenRightParen, "Expected ) after expression.")
 		return ast.GroupingExpr{Expression: expr}
+	case p.match(ast.TokenLeftBracket):
+		return p.list()
+	case p.match(ast.TokenLeftBrace):
+		return p.mapLiteral()
+	case p.match(ast.TokenInterpolation):
+		return p.interpolation()
 	}
 
 	p.error(p.peek(), "Expected expression.")
 	return nil
 }
 
+func (p *Parser) list() ast.Expr {
+	elements := make([]ast.Expr, 0)
+	if !p.check(ast.TokenRightBracket) {
+		for {
+			expr := p.expression()
+			elements = append(elements, expr)
+			if !p.match(ast.TokenComma) {
+				break
+			}
+		}
+	}
+	bracket := p.consume(ast.TokenRightBracket, "Expect ']' after list elements.")
+	return ast.ListExpr{Bracket: bracket, Elements: elements}
+}
+
+func (p *Parser) arrowFunction() ast.Expr {
+	keyword := p.advance()
+	parameters := p.parameters()
+	arrow := p.consume(ast.TokenArrow, "Expect '=>' after parameters.")
+
+	var body []ast.Stmt
+	isGenerator := false
+	if p.match(ast.TokenLeftBrace) {
+		body, isGenerator = p.functionBody()
+	} else {
+		value := p.expression()
+		body = []ast.Stmt{ast.ReturnStmt{Keyword: arrow, Value: value}}
+	}
+	return ast.FunctionExpr{Keyword: keyword, Params: parameters, Body: body, IsGenerator: isGenerator}
+}
+
+// isArrowFunction reports whether the '(' at the current position starts a
+// parameter list followed by '=>' rather than a grouping.
+func (p *Parser) isArrowFunction() bool {
+	i := p.current + 1
+	if p.tokens[i].TokenType != ast.TokenRightParen {
+		for {
+			if p.tokens[i].TokenType != ast.TokenIdentifier {
+				return false
+			}
+			i++
+			if p.tokens[i].TokenType != ast.TokenComma {
+				break
+			}
+			i++
+		}
+		if p.tokens[i].TokenType != ast.TokenRightParen {
+			return false
+		}
+	}
+	return p.tokens[i+1].TokenType == ast.TokenArrow
+}
+
+func (p *Parser) interpolation() ast.Expr {
+	parts := make([]ast.Expr, 0)
+	for {
+		if text := p.previous().Literal.(string); text != "" {
+			parts = append(parts, ast.LiteralExpr{Value: text})
+		}
+		parts = append(parts, p.expression())
+		if !p.match(ast.TokenInterpolation) {
+			break
+		}
+	}
+
+	end := p.consume(ast.TokenString, "Expect '}' after interpolated expression.")
+	if text := end.Literal.(string); text != "" {
+		parts = append(parts, ast.LiteralExpr{Value: text})
+	}
+	return ast.InterpolationExpr{Parts: parts}
+}
+
+func (p *Parser) mapLiteral() ast.Expr {
+	brace := p.previous()
+	keys := make([]ast.Expr, 0)
+	values := make([]ast.Expr, 0)
+	if !p.check(ast.TokenRightBrace) {
+		for {
+			key := p.expression()
+			p.consume(ast.TokenColon, "Expect ':' after map key.")
+			value := p.expression()
+			keys = append(keys, key)
+			values = append(values, value)
+			if !p.match(ast.TokenComma) {
+				break
+			}
+		}
+	}
+	p.consume(ast.TokenRightBrace, "Expect '}' after map entries.")
+	return ast.MapExpr{Brace: brace, Keys: keys, Values: values}
+}
+
+// isMapLiteral reports whether the '{' at the current position opens a map
+// literal rather than a block, i.e. it is followed by a literal key and ':'.
+func (p *Parser) isMapLiteral() bool {
+	if p.current+2 >= len(p.tokens) {
+		return false
+	}
+
+	switch p.peekNext().TokenType {
+	case ast.TokenString, ast.TokenNumber, ast.TokenTrue, ast.TokenFalse:
+		return p.tokens[p.current+2].TokenType == ast.TokenColon
+	}
+	return false
+}
+
 func (p *Parser) consume(tokenType ast.TokenType, message string) ast.Token {
 	if p.check(tokenType) {
 		return p.advance()
@@ -445,7 +935,7 @@ func (p *Parser) error(token ast.Token, message string) {
 		where = " at '" + token.Lexeme + "'"
 	}
 
-	err := fmt.Sprintf("[line %d] Error%s: %s\n", token.Line+1, where, message)
+	err := fmt.Sprintf("[line %d] Error%s: %s\n", token.Line, where, message)
 	_, _ = p.stdErr.Write([]byte(err))
 	panic(err)
 }
@@ -3,9 +3,14 @@ package resolve
 import (
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/Pra1tik/golox/ast"
 	"github.com/Pra1tik/golox/interpret"
+	"github.com/Pra1tik/golox/lexer"
+	"github.com/Pra1tik/golox/parser"
 )
 
 type scope map[string]bool
@@ -59,21 +64,34 @@ const (
 	classTypeNone classType = iota
 	classTypeClass
 	classTypeSubClass
+	classTypeTrait
 )
 
+// modules tracks imported files across the resolvers of every module.
+type modules struct {
+	loading map[string]bool // files currently being resolved, for cycle detection
+	loaded  map[string]bool
+}
+
 type Resolver struct {
 	interpreter *interpret.Interpreter
+	modules     *modules
 
 	scopes          scopes
 	currentFunction functionType
 	currentClass    classType
+	inStatic        bool
+	traits          []map[string]ast.TraitStmt // traits declared in each scope, globals first, for conflict detection
+	loopDepth       int
+	inGenerator     bool
 
 	stdErr   io.Writer
 	hadError bool
 }
 
 func CreateResolver(interpreter *interpret.Interpreter, stdErr io.Writer) *Resolver {
-	return &Resolver{interpreter: interpreter, stdErr: stdErr}
+	modules := &modules{loading: make(map[string]bool), loaded: make(map[string]bool)}
+	return &Resolver{interpreter: interpreter, modules: modules, traits: []map[string]ast.TraitStmt{make(map[string]ast.TraitStmt)}, stdErr: stdErr}
 }
 
 func (r *Resolver) VisitBlockStmt(stmt ast.BlockStmt) interface{} {
@@ -115,9 +133,18 @@ func (r *Resolver) VisitFunctionStmt(stmt ast.FunctionStmt) interface{} {
 	return nil
 }
 
+func (r *Resolver) VisitFunctionExpr(expr ast.FunctionExpr) interface{} {
+	r.resolveFunction(ast.FunctionStmt{Params: expr.Params, Body: expr.Body, IsGenerator: expr.IsGenerator}, functionTypeFunction)
+	return nil
+}
+
 func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt) interface{} {
 	enclosingClass := r.currentClass
-	defer func() { r.currentClass = enclosingClass }()
+	enclosingStatic := r.inStatic
+	defer func() {
+		r.currentClass = enclosingClass
+		r.inStatic = enclosingStatic
+	}()
 	r.currentClass = classTypeClass
 
 	r.declare(stmt.Name)
@@ -131,18 +158,42 @@ func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt) interface{} {
 		r.resolveExpr(stmt.Superclass)
 	}
 
+	for _, trait := range stmt.Traits {
+		if trait.Name.Lexeme == stmt.Name.Lexeme {
+			r.error(trait.Name, "A class can't use itself as a trait.")
+		}
+		r.resolveExpr(trait)
+	}
+	r.checkTraitConflicts(stmt)
+
+	// class fields are evaluated in the enclosing scope, after the class is defined
+	r.inStatic = true
+	for _, field := range stmt.Fields {
+		if field.Initializer != nil {
+			r.resolveExpr(field.Initializer)
+		}
+	}
+
 	if stmt.Superclass != nil {
 		r.beginScope()
 		defer func() { r.endScope() }()
 		r.scopes.peek().set("super")
 	}
 
+	for _, method := range stmt.StaticMethods {
+		r.resolveFunction(method, functionTypeMethod)
+	}
+	r.inStatic = false
+
 	r.beginScope()
 	r.scopes.peek().set("this")
 
 	for _, method := range stmt.Methods {
 		declaration := functionTypeMethod
 		if method.Name.Lexeme == "init" {
+			if method.IsGetter {
+				r.error(method.Name, "An initializer can't be a getter.")
+			}
 			declaration = functionTypeInitializer
 		}
 		r.resolveFunction(method, declaration)
@@ -153,6 +204,180 @@ func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt) interface{} {
 	return nil
 }
 
+func (r *Resolver) VisitTraitStmt(stmt ast.TraitStmt) interface{} {
+	enclosingClass := r.currentClass
+	enclosingStatic := r.inStatic
+	defer func() {
+		r.currentClass = enclosingClass
+		r.inStatic = enclosingStatic
+	}()
+	r.currentClass = classTypeTrait
+	r.inStatic = false
+
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.traits[len(r.traits)-1][stmt.Name.Lexeme] = stmt
+
+	r.beginScope()
+	r.scopes.peek().set("this")
+
+	for _, method := range stmt.Methods {
+		if method.Name.Lexeme == "init" {
+			r.error(method.Name, "A trait can't define an initializer.")
+		}
+		r.resolveFunction(method, functionTypeMethod)
+	}
+
+	r.endScope()
+
+	return nil
+}
+
+// checkTraitConflicts reports methods that two of the traits mixed into the
+// class both define, unless the class overrides them itself.
+func (r *Resolver) checkTraitConflicts(stmt ast.ClassStmt) {
+	own := make(map[string]bool, len(stmt.Methods))
+	for _, method := range stmt.Methods {
+		own[methodKey(method)] = true
+	}
+
+	providers := make(map[string]string)
+	for _, traitExpr := range stmt.Traits {
+		trait, ok := r.lookupTrait(traitExpr.Name.Lexeme)
+		if !ok {
+			continue // not a trait declared in this file; checked when the class is created
+		}
+
+		for _, method := range trait.Methods {
+			key := methodKey(method)
+			if own[key] {
+				continue
+			}
+			if provider, ok := providers[key]; ok {
+				r.error(traitExpr.Name, fmt.Sprintf("Method '%s' is defined by both traits '%s' and '%s'.", method.Name.Lexeme, provider, trait.Name.Lexeme))
+			}
+			providers[key] = trait.Name.Lexeme
+		}
+	}
+}
+
+// lookupTrait finds the trait the name refers to, if the innermost
+// declaration of the name is a trait declaration.
+func (r *Resolver) lookupTrait(name string) (ast.TraitStmt, bool) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if declared, _ := r.scopes[i].has(name); declared {
+			trait, ok := r.traits[i+1][name]
+			return trait, ok
+		}
+	}
+	trait, ok := r.traits[0][name]
+	return trait, ok
+}
+
+// methodKey distinguishes a setter from a method or getter of the same name.
+func methodKey(method ast.FunctionStmt) string {
+	if method.IsSetter {
+		return "set " + method.Name.Lexeme
+	}
+	return method.Name.Lexeme
+}
+
+func (r *Resolver) VisitImportStmt(stmt ast.ImportStmt) interface{} {
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	// relative paths are relative to the importing file, not the working directory
+	path := stmt.Path.Literal.(string)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(stmt.Keyword.File), path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		r.error(stmt.Path, "Invalid module path.")
+		return nil
+	}
+
+	if stmt.Keyword.File != "" {
+		// the importing file is still being resolved, even if it is the main script
+		if importer, err := filepath.Abs(stmt.Keyword.File); err == nil {
+			r.modules.loading[importer] = true
+		}
+	}
+
+	if r.modules.loading[path] {
+		r.error(stmt.Path, "Import cycle detected.")
+		return nil
+	}
+	if !r.modules.loaded[path] && !r.loadModule(stmt.Path, path) {
+		return nil
+	}
+
+	r.interpreter.ResolveImport(stmt.Keyword, path)
+	return nil
+}
+
+// loadModule lexes, parses and resolves the file at path and hands it to the
+// interpreter. It reports whether the module is ready to be imported.
+func (r *Resolver) loadModule(token ast.Token, path string) bool {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		r.error(token, fmt.Sprintf("Can't read module '%s'.", path))
+		return false
+	}
+
+	r.modules.loading[path] = true
+	defer delete(r.modules.loading, path)
+
+	tokens, hadError := lexer.CreateFileScanner(path, string(source), r.stdErr).ScanTokens()
+	if hadError {
+		r.hadError = true
+		return false
+	}
+
+	statements, hadError := parser.CreateParser(tokens, r.stdErr).Parse()
+	if hadError {
+		r.hadError = true
+		return false
+	}
+
+	resolver := &Resolver{interpreter: r.interpreter, modules: r.modules, traits: []map[string]ast.TraitStmt{make(map[string]ast.TraitStmt)}, stdErr: r.stdErr}
+	if resolver.ResolveStmts(statements) {
+		r.hadError = true
+		return false
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	r.interpreter.AddModule(path, name, statements)
+	r.modules.loaded[path] = true
+	return true
+}
+
+func (r *Resolver) VisitThrowStmt(stmt ast.ThrowStmt) interface{} {
+	r.resolveExpr(stmt.Value)
+	return nil
+}
+
+func (r *Resolver) VisitTryStmt(stmt ast.TryStmt) interface{} {
+	r.beginScope()
+	r.ResolveStmts(stmt.Body)
+	r.endScope()
+
+	if stmt.CatchName != nil {
+		r.beginScope()
+		r.declare(*stmt.CatchName)
+		r.define(*stmt.CatchName)
+		r.ResolveStmts(stmt.CatchBody)
+		r.endScope()
+	}
+
+	if stmt.FinallyBody != nil {
+		r.beginScope()
+		r.ResolveStmts(stmt.FinallyBody)
+		r.endScope()
+	}
+	return nil
+}
+
 func (r *Resolver) VisitExpressionStmt(stmt ast.ExpressionStmt) interface{} {
 	r.resolveExpr(stmt.Expr)
 	return nil
@@ -181,6 +406,23 @@ func (r *Resolver) VisitReturnStmt(stmt ast.ReturnStmt) interface{} {
 		if r.currentFunction == functionTypeInitializer {
 			r.error(stmt.Keyword, "Can't return value from initializer.")
 		}
+		if r.inGenerator {
+			r.error(stmt.Keyword, "Can't return value from generator.")
+		}
+		r.resolveExpr(stmt.Value)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitYieldStmt(stmt ast.YieldStmt) interface{} {
+	switch r.currentFunction {
+	case functionTypeNone:
+		r.error(stmt.Keyword, "Can't yield from top-level code.")
+	case functionTypeInitializer:
+		r.error(stmt.Keyword, "Can't yield from initializer.")
+	}
+
+	if stmt.Value != nil {
 		r.resolveExpr(stmt.Value)
 	}
 	return nil
@@ -188,7 +430,39 @@ func (r *Resolver) VisitReturnStmt(stmt ast.ReturnStmt) interface{} {
 
 func (r *Resolver) VisitWhileStmt(stmt ast.WhileStmt) interface{} {
 	r.resolveExpr(stmt.Condition)
+	r.loopDepth++
+	r.resolveStmt(stmt.Body)
+	r.loopDepth--
+	if stmt.Increment != nil {
+		r.resolveExpr(stmt.Increment)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitForInStmt(stmt ast.ForInStmt) interface{} {
+	r.resolveExpr(stmt.Iterable)
+
+	r.beginScope()
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.loopDepth++
 	r.resolveStmt(stmt.Body)
+	r.loopDepth--
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitBreakStmt(stmt ast.BreakStmt) interface{} {
+	if r.loopDepth == 0 {
+		r.error(stmt.Keyword, "Can't use 'break' outside of a loop.")
+	}
+	return nil
+}
+
+func (r *Resolver) VisitContinueStmt(stmt ast.ContinueStmt) interface{} {
+	if r.loopDepth == 0 {
+		r.error(stmt.Keyword, "Can't use 'continue' outside of a loop.")
+	}
 	return nil
 }
 
@@ -199,13 +473,13 @@ func (r *Resolver) VisitVariableExpr(expr ast.VariableExpr) interface{} {
 		}
 	}
 
-	r.resolveLocal(expr, expr.Name)
+	r.resolveLocal(expr.Name)
 	return nil
 }
 
 func (r *Resolver) VisitAssignExpr(expr ast.AssignExpr) interface{} {
 	r.resolveExpr(expr.Value)
-	r.resolveLocal(expr, expr.Name)
+	r.resolveLocal(expr.Name)
 	return nil
 }
 
@@ -235,20 +509,26 @@ func (r *Resolver) VisitLiteralExpr(expr ast.LiteralExpr) interface{} {
 func (r *Resolver) VisitThisExpr(expr ast.ThisExpr) interface{} {
 	if r.currentClass == classTypeNone {
 		r.error(expr.Keyword, "Can't use 'this' outside of a class.")
+	} else if r.inStatic {
+		r.error(expr.Keyword, "Can't use 'this' in a static method or class field.")
 	}
 
-	r.resolveLocal(expr, expr.Keyword)
+	r.resolveLocal(expr.Keyword)
 	return nil
 }
 
 func (r *Resolver) VisitSuperExpr(expr ast.SuperExpr) interface{} {
 	if r.currentClass == classTypeNone {
 		r.error(expr.Keyword, "Can't use 'super' outside of a class.")
+	} else if r.currentClass == classTypeTrait {
+		r.error(expr.Keyword, "Can't use 'super' in a trait.")
 	} else if r.currentClass != classTypeSubClass {
 		r.error(expr.Keyword, "Can't use 'super' in a class with no superclass")
+	} else if r.inStatic {
+		r.error(expr.Keyword, "Can't use 'super' in a static method or class field.")
 	}
 
-	r.resolveLocal(expr, expr.Keyword)
+	r.resolveLocal(expr.Keyword)
 	return nil
 }
 
@@ -258,6 +538,26 @@ func (r *Resolver) VisitLogicalExpr(expr ast.LogicalExpr) interface{} {
 	return nil
 }
 
+func (r *Resolver) VisitConditionalExpr(expr ast.ConditionalExpr) interface{} {
+	r.resolveExpr(expr.Condition)
+	r.resolveExpr(expr.ThenBranch)
+	r.resolveExpr(expr.ElseBranch)
+	return nil
+}
+
+func (r *Resolver) VisitInterpolationExpr(expr ast.InterpolationExpr) interface{} {
+	for _, part := range expr.Parts {
+		r.resolveExpr(part)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitCompoundExpr(expr ast.CompoundExpr) interface{} {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Target)
+	return nil
+}
+
 func (r *Resolver) VisitUnaryExpr(expr ast.UnaryExpr) interface{} {
 	r.resolveExpr(expr.Right)
 	return nil
@@ -274,12 +574,40 @@ func (r *Resolver) VisitSetExpr(expr ast.SetExpr) interface{} {
 	return nil
 }
 
-func (r *Resolver) resolveLocal(expr ast.Expr, name ast.Token) {
+func (r *Resolver) VisitListExpr(expr ast.ListExpr) interface{} {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitIndexExpr(expr ast.IndexExpr) interface{} {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+
+func (r *Resolver) VisitIndexSetExpr(expr ast.IndexSetExpr) interface{} {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+
+func (r *Resolver) VisitMapExpr(expr ast.MapExpr) interface{} {
+	for i := range expr.Keys {
+		r.resolveExpr(expr.Keys[i])
+		r.resolveExpr(expr.Values[i])
+	}
+	return nil
+}
+
+func (r *Resolver) resolveLocal(name ast.Token) {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
 		s := r.scopes[i]
 		if _, defined := s.has(name.Lexeme); defined {
 			depth := len(r.scopes) - 1 - i
-			r.interpreter.Resolve(expr, depth)
+			r.interpreter.Resolve(name, depth)
 			return
 		}
 	}
@@ -287,8 +615,16 @@ func (r *Resolver) resolveLocal(expr ast.Expr, name ast.Token) {
 
 func (r *Resolver) resolveFunction(function ast.FunctionStmt, fnType functionType) {
 	enclosingFunction := r.currentFunction
+	enclosingLoopDepth := r.loopDepth
+	enclosingGenerator := r.inGenerator
 	r.currentFunction = fnType
-	defer func() { r.currentFunction = enclosingFunction }()
+	r.loopDepth = 0
+	r.inGenerator = function.IsGenerator
+	defer func() {
+		r.currentFunction = enclosingFunction
+		r.loopDepth = enclosingLoopDepth
+		r.inGenerator = enclosingGenerator
+	}()
 
 	r.beginScope()
 	for _, param := range function.Params {
@@ -300,6 +636,8 @@ func (r *Resolver) resolveFunction(function ast.FunctionStmt, fnType functionTyp
 }
 
 func (r *Resolver) declare(name ast.Token) {
+	// a trait declaration records itself after declaring its name
+	delete(r.traits[len(r.traits)-1], name.Lexeme)
 	if len(r.scopes) == 0 {
 		return
 	}
@@ -322,10 +660,12 @@ func (r *Resolver) define(name ast.Token) {
 
 func (r *Resolver) beginScope() {
 	r.scopes.push(make(scope))
+	r.traits = append(r.traits, make(map[string]ast.TraitStmt))
 }
 
 func (r *Resolver) endScope() {
 	r.scopes.pop()
+	r.traits = r.traits[:len(r.traits)-1]
 }
 
 func (r *Resolver) error(token ast.Token, message string) {
@@ -336,6 +676,11 @@ func (r *Resolver) error(token ast.Token, message string) {
 		where = " at '" + token.Lexeme + "'"
 	}
 
-	_, _ = r.stdErr.Write([]byte(fmt.Sprintf("[line %d] Error%s: %s\n", token.Line, where, message)))
+	line := fmt.Sprintf("line %d", token.Line)
+	if token.File != "" {
+		line += " in " + token.File
+	}
+
+	_, _ = r.stdErr.Write([]byte(fmt.Sprintf("[%s] Error%s: %s\n", line, where, message)))
 	r.hadError = true
 }